@@ -0,0 +1,253 @@
+package bmf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrPacked is returned when an operation on the page images does not support packed fonts,
+// where each glyph is stored in a single color channel
+var ErrPacked = errors.New("packed fonts are not supported")
+
+// shelfPacker places rectangles left to right in rows (shelves) on pages of a fixed size
+type shelfPacker struct {
+	width, height int
+	spacing       Spacing
+	page          int
+	x, y          int
+	shelfHeight   int
+}
+
+// place returns the position of a w*h rectangle, starting a new shelf or page when necessary
+func (p *shelfPacker) place(w, h int) (page, x, y int, err error) {
+	if w > p.width || h > p.height {
+		return 0, 0, 0, fmt.Errorf("glyph of size %dx%d does not fit on a page of size %dx%d", w, h, p.width, p.height)
+	}
+
+	if p.x+w > p.width {
+		p.x = 0
+		p.y += p.shelfHeight + p.spacing.Vertical
+		p.shelfHeight = 0
+	}
+	if p.y+h > p.height {
+		p.page++
+		p.x, p.y = 0, 0
+		p.shelfHeight = 0
+	}
+
+	page, x, y = p.page, p.x, p.y
+	p.x += w + p.spacing.Horizontal
+	if h > p.shelfHeight {
+		p.shelfHeight = h
+	}
+	return page, x, y, nil
+}
+
+// packOrder returns the indices of the chars sorted by decreasing height and width,
+// which keeps the shelves tight
+func packOrder(chars []Char) []int {
+	order := make([]int, len(chars))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := chars[order[i]], chars[order[j]]
+		if a.Height != b.Height {
+			return a.Height > b.Height
+		}
+		return a.Width > b.Width
+	})
+	return order
+}
+
+// pack assigns new positions to all chars on pages of the given size.
+// Chars without pixels are placed at the origin of the first page.
+// It returns the number of pages used, which is at least one.
+func pack(chars []Char, width, height int, spacing Spacing) (pages int, err error) {
+	packer := &shelfPacker{width: width, height: height, spacing: spacing}
+	for _, i := range packOrder(chars) {
+		c := &chars[i]
+		if c.Width <= 0 || c.Height <= 0 {
+			c.Page, c.X, c.Y = 0, 0, 0
+			continue
+		}
+		if c.Page, c.X, c.Y, err = packer.place(c.Width, c.Height); err != nil {
+			return 0, fmt.Errorf("char %d: %w", c.Id, err)
+		}
+	}
+	return packer.page + 1, nil
+}
+
+// glyphBounds returns the rectangle of a char on its page
+func glyphBounds(c Char) image.Rectangle {
+	return image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height)
+}
+
+// checkGlyphs makes sure that all chars reference an existing page and lie within it
+func checkGlyphs(fnt *Font, pages []*image.NRGBA) error {
+	if fnt.Common.Packed {
+		return ErrPacked
+	}
+	for _, c := range fnt.Chars {
+		if c.Width <= 0 || c.Height <= 0 {
+			continue
+		}
+		if c.Page < 0 || c.Page >= len(pages) || pages[c.Page] == nil {
+			return fmt.Errorf("char %d: page %d does not exist", c.Id, c.Page)
+		}
+		bounds := pages[c.Page].Bounds()
+		if !glyphBounds(c).Add(bounds.Min).In(bounds) {
+			return fmt.Errorf("char %d: glyph %v is outside of page %d", c.Id, glyphBounds(c), c.Page)
+		}
+	}
+	return nil
+}
+
+// newPages packs the chars of fnt onto pages of the given size, replacing fnt.Pages with pages
+// named by the fmt pattern, and returns blank images for them
+func newPages(fnt *Font, width, height int, pattern string) ([]*image.NRGBA, error) {
+	pageCount, err := pack(fnt.Chars, width, height, fnt.Info.Spacing)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]*image.NRGBA, pageCount)
+	fnt.Pages = make([]Page, pageCount)
+	for i := range images {
+		images[i] = image.NewNRGBA(image.Rect(0, 0, width, height))
+		fnt.Pages[i] = Page{Id: i, File: fmt.Sprintf(pattern, i)}
+	}
+	fnt.Common.Pages = pageCount
+	return images, nil
+}
+
+// pageFilePattern derives a format for new page file names from the first page,
+// e.g. "font_0.png" becomes "font_%d.png"
+func pageFilePattern(fnt *Font) string {
+	if len(fnt.Pages) == 0 {
+		return "page_%d.png"
+	}
+	file := fnt.Pages[0].File
+	ext := path.Ext(file)
+	base := strings.TrimRight(strings.TrimSuffix(file, ext), "0123456789")
+	return strings.ReplaceAll(base, "%", "%%") + "%d" + strings.ReplaceAll(ext, "%", "%%")
+}
+
+// RepackOptions controls the layout of the pages created by Repack
+type RepackOptions struct {
+	// Width and Height of the new pages in pixels
+	Width, Height int
+	// PageFile is the fmt format for the new page file names, given the page id.
+	// It defaults to the naming of the existing pages, e.g. "font_%d.png" for "font_0.png".
+	PageFile string
+}
+
+// Repack copies the glyphs of fnt from pages onto new pages of the size given in opts.
+// The padding from Info.Padding is part of the glyph rectangles and is copied along with them,
+// the spacing from Info.Spacing is kept free between glyphs.
+// It returns an updated copy of fnt, where the chars, Common and Pages match the new page images.
+func Repack(fnt *Font, pages []*image.NRGBA, opts RepackOptions) (*Font, []*image.NRGBA, error) {
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return nil, nil, fmt.Errorf("invalid page size %dx%d", opts.Width, opts.Height)
+	}
+	if opts.PageFile == "" {
+		opts.PageFile = pageFilePattern(fnt)
+	}
+	if err := checkGlyphs(fnt, pages); err != nil {
+		return nil, nil, err
+	}
+
+	out := *fnt
+	out.Chars = append([]Char(nil), fnt.Chars...)
+	out.Kernings = append([]Kerning(nil), fnt.Kernings...)
+
+	images, err := newPages(&out, opts.Width, opts.Height, opts.PageFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, c := range out.Chars {
+		if c.Width <= 0 || c.Height <= 0 {
+			continue
+		}
+		src := fnt.Chars[i]
+		srcImg := pages[src.Page]
+		draw.Draw(images[c.Page], glyphBounds(c), srcImg, srcImg.Bounds().Min.Add(image.Pt(src.X, src.Y)), draw.Src)
+	}
+
+	out.Common.ScaleW = opts.Width
+	out.Common.ScaleH = opts.Height
+	return &out, images, nil
+}
+
+// LoadPages decodes the page images of fnt from fsys, indexed by page id.
+// The file names are relative to the root of fsys, usually the directory of the font file.
+func LoadPages(fsys fs.FS, fnt *Font) ([]*image.NRGBA, error) {
+	pages := make([]*image.NRGBA, len(fnt.Pages))
+	for _, p := range fnt.Pages {
+		if p.Id < 0 || p.Id >= len(pages) {
+			return nil, fmt.Errorf("page %q: expected id to be between 0 and %d but was %d", p.File, len(pages)-1, p.Id)
+		}
+
+		img, err := loadPage(fsys, p.File)
+		if err != nil {
+			return nil, fmt.Errorf("page %q: %w", p.File, err)
+		}
+		pages[p.Id] = img
+	}
+	return pages, nil
+}
+
+func loadPage(fsys fs.FS, file string) (*image.NRGBA, error) {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba, nil
+	}
+
+	nrgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return nrgba, nil
+}
+
+// SavePages encodes the page images of fnt as PNG files in dir
+func SavePages(dir string, fnt *Font, pages []*image.NRGBA) error {
+	for _, p := range fnt.Pages {
+		if p.Id < 0 || p.Id >= len(pages) || pages[p.Id] == nil {
+			return fmt.Errorf("page %q: no image for id %d", p.File, p.Id)
+		}
+		if err := savePage(filepath.Join(dir, filepath.FromSlash(p.File)), pages[p.Id]); err != nil {
+			return fmt.Errorf("page %q: %w", p.File, err)
+		}
+	}
+	return nil
+}
+
+func savePage(file string, img *image.NRGBA) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,10 +1,13 @@
 package bmf
 
 import (
+	"bytes"
+	"context"
 	encoding "encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/Qendolin/go-bmf/internal/binary"
@@ -32,13 +35,15 @@ func (e BinaryParseError) Unwrap() error {
 // BlockType specifies the type of a binary block
 type BlockType byte
 
+// Block types of the binary format.
+// BlockHeader is the file header, which is not a block but is reported like one in a BinaryParseError.
 const (
-	blockHeader       BlockType = 0
-	blockInfo         BlockType = 1
-	blockCommon       BlockType = 2
-	blockPages        BlockType = 3
-	blockChars        BlockType = 4
-	blockKerningPairs BlockType = 5
+	BlockHeader       BlockType = 0
+	BlockInfo         BlockType = 1
+	BlockCommon       BlockType = 2
+	BlockPages        BlockType = 3
+	BlockChars        BlockType = 4
+	BlockKerningPairs BlockType = 5
 )
 
 // Name returns the name of the block
@@ -46,22 +51,93 @@ func (typ BlockType) Name() string {
 	return blockNameTable[typ]
 }
 
+// String returns the name of the block or its number if it is unknown
+func (typ BlockType) String() string {
+	if name, ok := blockNameTable[typ]; ok {
+		return name
+	}
+	return strconv.Itoa(int(typ))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (typ BlockType) MarshalText() ([]byte, error) {
+	return []byte(typ.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It accepts the names returned by String and numbers.
+func (typ *BlockType) UnmarshalText(text []byte) error {
+	s := string(text)
+	for t, name := range blockNameTable {
+		if strings.EqualFold(s, name) {
+			*typ = t
+			return nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > 0xff {
+		return fmt.Errorf("invalid block type %q", s)
+	}
+	*typ = BlockType(v)
+	return nil
+}
+
 var blockNameTable = map[BlockType]string{
-	blockHeader:       "header",
-	blockInfo:         "info",
-	blockCommon:       "common",
-	blockPages:        "pages",
-	blockChars:        "characters",
-	blockKerningPairs: "kerning pairs",
+	BlockHeader:       "header",
+	BlockInfo:         "info",
+	BlockCommon:       "common",
+	BlockPages:        "pages",
+	BlockChars:        "characters",
+	BlockKerningPairs: "kerning pairs",
 }
 
 // ParseBinary parses a bmf font definition in binary format.
 // For more information see http://www.angelcode.com/products/bmfont/doc/file_format.html#bin
-func ParseBinary(src io.Reader) (fnt *Font, err error) {
-	fileReader := &binary.Reader{
-		Src:   src,
+func ParseBinary(src io.Reader) (*Font, error) {
+	return ParseOptions{}.ParseBinary(src)
+}
+
+// ParseBinary parses a bmf font definition in binary format.
+// For more information see http://www.angelcode.com/products/bmfont/doc/file_format.html#bin
+func (opts ParseOptions) ParseBinary(src io.Reader) (*Font, error) {
+	return opts.ParseBinaryContext(context.Background(), src)
+}
+
+// ParseBinaryContext parses a bmf font definition in binary format and stops once ctx is done.
+// The error of ctx is returned wrapped in a BinaryParseError.
+func ParseBinaryContext(ctx context.Context, src io.Reader) (*Font, error) {
+	return ParseOptions{}.ParseBinaryContext(ctx, src)
+}
+
+// ParseBinaryContext parses a bmf font definition in binary format and stops once ctx is done.
+// The error of ctx is returned wrapped in a BinaryParseError.
+func (opts ParseOptions) ParseBinaryContext(ctx context.Context, src io.Reader) (*Font, error) {
+	return parseBinary(ctx, &binary.Reader{
+		Src:   withContext(ctx, opts.limitInput(src)),
 		Order: encoding.LittleEndian,
+	}, opts)
+}
+
+// ParseBinaryBytes parses a bmf font definition in binary format.
+// The blocks are decoded directly from data without copying it.
+func ParseBinaryBytes(data []byte) (*Font, error) {
+	return ParseOptions{}.ParseBinaryBytes(data)
+}
+
+// ParseBinaryBytes parses a bmf font definition in binary format.
+// The blocks are decoded directly from data without copying it.
+func (opts ParseOptions) ParseBinaryBytes(data []byte) (*Font, error) {
+	if err := opts.checkInputSize(len(data)); err != nil {
+		return nil, err
 	}
+
+	return parseBinary(context.Background(), &binary.Reader{
+		Buf:   data,
+		Order: encoding.LittleEndian,
+	}, opts)
+}
+
+func parseBinary(ctx context.Context, fileReader *binary.Reader, opts ParseOptions) (fnt *Font, err error) {
 	fnt = &Font{}
 
 	if err := parseHeaderBinary(fileReader); err != nil {
@@ -69,7 +145,7 @@ func ParseBinary(src io.Reader) (fnt *Font, err error) {
 	}
 
 	for {
-		err = parseBlockBinary(fnt, fileReader)
+		err = parseBlockBinary(ctx, fnt, fileReader, opts)
 		if errors.Is(err, io.EOF) {
 			break
 		}
@@ -81,17 +157,21 @@ func ParseBinary(src io.Reader) (fnt *Font, err error) {
 	return fnt, nil
 }
 
-func parseBlockBinary(fnt *Font, fileReader *binary.Reader) (err error) {
+func parseBlockBinary(ctx context.Context, fnt *Font, fileReader *binary.Reader, opts ParseOptions) (err error) {
 	var (
-		blockId   int
-		blockType BlockType
-		blockLen  int
+		blockType   BlockType
+		blockLen    int
+		blockReader *binary.Reader
 	)
 
 	defer func() {
 		if err != nil {
+			offset := fileReader.Index
+			if blockReader != nil {
+				offset = fileReader.Index - blockLen + blockReader.Index
+			}
 			err = BinaryParseError{
-				Offset:      fileReader.Index,
+				Offset:      offset,
 				Block:       blockType,
 				BlockLength: blockLen,
 				Err:         err,
@@ -99,59 +179,102 @@ func parseBlockBinary(fnt *Font, fileReader *binary.Reader) (err error) {
 		}
 	}()
 
-	if !fileReader.ReadUInt8(&blockId) {
+	if !fileReader.Fill(5) {
 		if errors.Is(fileReader.Err, io.EOF) {
 			return fileReader.Err
 		}
+		return fmt.Errorf("expected one byte for block type identifier and four bytes for block length: %w", fileReader.Err)
+	}
 
-		return fmt.Errorf("expected one byte for block type identifier")
+	blockType, blockLen, err = parseBlockHeaderBinary(fileReader)
+	if err != nil {
+		return err
+	}
+	if err := opts.checkBlockBinary(blockType, blockLen); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !fileReader.Fill(blockLen) {
+		return fmt.Errorf("expected %d bytes for block but got %d: %w", blockLen, fileReader.Len(), fileReader.Err)
+	}
+	block, _ := fileReader.Read(blockLen)
+	blockReader = &binary.Reader{
+		Buf:   block,
+		Order: encoding.LittleEndian,
+	}
+
+	if err := opts.decodeBlockBinary(fnt, blockType, blockReader, blockLen); err != nil {
+		return err
+	}
+	return opts.checkFont(fnt)
+}
+
+// parseBlockHeaderBinary reads the type and length of a block from the next five bytes
+func parseBlockHeaderBinary(brd *binary.Reader) (blockType BlockType, blockLen int, err error) {
+	var blockId int
+	if !brd.ReadUInt8(&blockId) {
+		return 0, 0, fmt.Errorf("expected one byte for block type identifier")
 	}
 	if blockId < 1 || blockId > 5 {
-		return fmt.Errorf("expected block type to be one of 1,2,3,4,5 but was %d", blockId)
+		return 0, 0, fmt.Errorf("expected block type to be one of 1,2,3,4,5 but was %d", blockId)
 	}
 
 	blockType = BlockType(blockId)
-	if !fileReader.ReadUInt32(&blockLen) {
-		return fmt.Errorf("expected four bytes for block length")
+	if !brd.ReadUInt32(&blockLen) {
+		return blockType, 0, fmt.Errorf("expected four bytes for block length")
 	}
+	return blockType, blockLen, nil
+}
 
-	blockReader := &binary.Reader{
-		Src:   fileReader.Src,
-		Order: encoding.LittleEndian,
+// checkBlockBinary checks the limits that can be derived from the length of a block before it is read
+func (opts ParseOptions) checkBlockBinary(blockType BlockType, blockLen int) error {
+	switch blockType {
+	case BlockInfo:
+		return opts.checkStringLength(blockLen - 15)
+	case BlockPages:
+		if opts.MaxPages > 0 && opts.MaxStringLength > 0 && blockLen > opts.MaxPages*(opts.MaxStringLength+1) {
+			return LimitError{Limit: "MaxPages", Max: opts.MaxPages}
+		}
+	case BlockChars:
+		return opts.checkCounts(blockLen/20, 0, 0)
+	case BlockKerningPairs:
+		return opts.checkCounts(0, blockLen/10, 0)
 	}
+	return nil
+}
 
+// decodeBlockBinary parses the contents of a block into the matching field of fnt
+func (opts ParseOptions) decodeBlockBinary(fnt *Font, blockType BlockType, blockReader *binary.Reader, blockLen int) error {
 	switch blockType {
-	case blockInfo:
+	case BlockInfo:
 		info, err := parseInfoBinary(blockReader, blockLen)
-		fileReader.Index += blockReader.Index
 		if err != nil {
 			return err
 		}
 		fnt.Info = *info
-	case blockCommon:
+	case BlockCommon:
 		common, err := parseCommonBinary(blockReader, blockLen)
-		fileReader.Index += blockReader.Index
 		if err != nil {
 			return err
 		}
 		fnt.Common = *common
-	case blockPages:
-		pages, err := parsePagesBinary(blockReader, blockLen)
-		fileReader.Index += blockReader.Index
+	case BlockPages:
+		pages, err := parsePagesBinary(blockReader, blockLen, opts)
 		if err != nil {
 			return err
 		}
 		fnt.Pages = pages
-	case blockChars:
+	case BlockChars:
 		chars, err := parseCharsBinary(blockReader, blockLen)
-		fileReader.Index += blockReader.Index
 		if err != nil {
 			return err
 		}
 		fnt.Chars = chars
-	case blockKerningPairs:
+	case BlockKerningPairs:
 		kernings, err := parseKerningPairsBinary(blockReader, blockLen)
-		fileReader.Index += blockReader.Index
 		if err != nil {
 			return err
 		}
@@ -161,18 +284,186 @@ func parseBlockBinary(fnt *Font, fileReader *binary.Reader) (err error) {
 	return nil
 }
 
+// BinaryBlocks provides random access to the blocks of a binary font.
+// Opening it only reads the block headers, the blocks themselves are read and parsed on demand.
+type BinaryBlocks struct {
+	opts   ParseOptions
+	src    io.ReaderAt
+	blocks map[BlockType]binaryBlock
+}
+
+type binaryBlock struct {
+	offset int64
+	length int
+}
+
+// OpenBinary indexes the blocks of a binary font definition with the given size in bytes.
+// The block lengths are used to skip from one block header to the next.
+func OpenBinary(src io.ReaderAt, size int64) (*BinaryBlocks, error) {
+	return ParseOptions{}.OpenBinary(src, size)
+}
+
+// OpenBinary indexes the blocks of a binary font definition with the given size in bytes.
+// The block lengths are used to skip from one block header to the next.
+func (opts ParseOptions) OpenBinary(src io.ReaderAt, size int64) (*BinaryBlocks, error) {
+	if err := opts.checkInputSize(int(size)); err != nil {
+		return nil, err
+	}
+	if err := parseHeaderBinary(&binary.Reader{
+		Src:   io.NewSectionReader(src, 0, size),
+		Order: encoding.LittleEndian,
+	}); err != nil {
+		return nil, err
+	}
+
+	bb := &BinaryBlocks{
+		opts:   opts,
+		src:    src,
+		blocks: map[BlockType]binaryBlock{},
+	}
+
+	for offset := int64(4); offset < size; {
+		blockReader := &binary.Reader{
+			Src:   io.NewSectionReader(src, offset, size-offset),
+			Order: encoding.LittleEndian,
+		}
+		var (
+			blockType BlockType
+			blockLen  int
+			err       error
+		)
+		if blockReader.Fill(5) {
+			blockType, blockLen, err = parseBlockHeaderBinary(blockReader)
+			if err == nil {
+				err = opts.checkBlockBinary(blockType, blockLen)
+			}
+		} else {
+			err = fmt.Errorf("expected one byte for block type identifier and four bytes for block length: %w", blockReader.Err)
+		}
+		if err != nil {
+			return nil, BinaryParseError{
+				Offset:      int(offset) + blockReader.Index,
+				Block:       blockType,
+				BlockLength: blockLen,
+				Err:         err,
+			}
+		}
+
+		offset += 5
+		if int64(blockLen) > size-offset {
+			return nil, BinaryParseError{
+				Offset:      int(size),
+				Block:       blockType,
+				BlockLength: blockLen,
+				Err:         fmt.Errorf("expected %d bytes for block but got %d: %w", blockLen, size-offset, io.ErrUnexpectedEOF),
+			}
+		}
+
+		bb.blocks[blockType] = binaryBlock{
+			offset: offset,
+			length: blockLen,
+		}
+		offset += int64(blockLen)
+	}
+
+	return bb, nil
+}
+
+// parse reads a single block and parses it into fnt.
+// Missing blocks are ignored.
+func (bb *BinaryBlocks) parse(fnt *Font, blockType BlockType) error {
+	block, ok := bb.blocks[blockType]
+	if !ok {
+		return nil
+	}
+
+	blockReader := &binary.Reader{
+		Src:   io.NewSectionReader(bb.src, block.offset, int64(block.length)),
+		Order: encoding.LittleEndian,
+	}
+	var err error
+	if blockReader.Fill(block.length) {
+		err = bb.opts.decodeBlockBinary(fnt, blockType, blockReader, block.length)
+		if err == nil {
+			err = bb.opts.checkFont(fnt)
+		}
+	} else {
+		err = fmt.Errorf("expected %d bytes for block but got %d: %w", block.length, blockReader.Len(), blockReader.Err)
+	}
+
+	if err != nil {
+		return BinaryParseError{
+			Offset:      int(block.offset) + blockReader.Index,
+			Block:       blockType,
+			BlockLength: block.length,
+			Err:         err,
+		}
+	}
+	return nil
+}
+
+// Info reads and parses the info block
+func (bb *BinaryBlocks) Info() (Info, error) {
+	fnt := &Font{}
+	err := bb.parse(fnt, BlockInfo)
+	return fnt.Info, err
+}
+
+// Common reads and parses the common block
+func (bb *BinaryBlocks) Common() (Common, error) {
+	fnt := &Font{}
+	err := bb.parse(fnt, BlockCommon)
+	return fnt.Common, err
+}
+
+// Pages reads and parses the pages block
+func (bb *BinaryBlocks) Pages() ([]Page, error) {
+	fnt := &Font{}
+	err := bb.parse(fnt, BlockPages)
+	return fnt.Pages, err
+}
+
+// Chars reads and parses the chars block
+func (bb *BinaryBlocks) Chars() ([]Char, error) {
+	fnt := &Font{}
+	err := bb.parse(fnt, BlockChars)
+	return fnt.Chars, err
+}
+
+// Kernings reads and parses the kerning pairs block
+func (bb *BinaryBlocks) Kernings() ([]Kerning, error) {
+	fnt := &Font{}
+	err := bb.parse(fnt, BlockKerningPairs)
+	return fnt.Kernings, err
+}
+
+// Font reads and parses all blocks
+func (bb *BinaryBlocks) Font() (*Font, error) {
+	fnt := &Font{}
+	for _, blockType := range []BlockType{BlockInfo, BlockCommon, BlockPages, BlockChars, BlockKerningPairs} {
+		if err := bb.parse(fnt, blockType); err != nil {
+			return nil, err
+		}
+	}
+	return fnt, nil
+}
+
 func parseHeaderBinary(frd *binary.Reader) (err error) {
 	defer func() {
 		if err != nil {
 			err = BinaryParseError{
 				Offset:      frd.Index,
-				Block:       blockHeader,
+				Block:       BlockHeader,
 				BlockLength: 4,
 				Err:         err,
 			}
 		}
 	}()
 
+	if !frd.Fill(4) {
+		return fmt.Errorf("expected four bytes for the file header: %w", frd.Err)
+	}
+
 	var start string
 	if !frd.ReadString(&start, 3) {
 		return fmt.Errorf("expected three bytes for the file identifier")
@@ -212,10 +503,11 @@ func parseInfoBinary(brd *binary.Reader, blockLength int) (*Info, error) {
 	info.Bold = Bool(int(flags >> 4 & 0x1))
 	//FIXME: Unused "fixedHeigth" bit
 
+	// unicode fonts usually have no charset, which is stored as 0
 	if charSet := 0; !brd.ReadUInt8(&charSet) {
 		return nil, fmt.Errorf("expected one byte for charSet")
-	} else if !info.Unicode {
-		info.Charset, _ = LookupCharset(charSet)
+	} else if !info.Unicode || charSet != 0 {
+		info.Charset = NewCharset(uint8(charSet))
 	}
 
 	if !brd.ReadUInt16(&info.StretchH) {
@@ -248,6 +540,9 @@ func parseInfoBinary(brd *binary.Reader, blockLength int) (*Info, error) {
 
 	fontNameLen := blockLength - brd.Index
 	fontName := ""
+	if fontNameLen < 1 {
+		return nil, fmt.Errorf("expected at least one byte for fontName")
+	}
 	if !brd.ReadString(&fontName, fontNameLen) {
 		return nil, fmt.Errorf("expected %d bytes for fontName", fontNameLen)
 	} else if fontName[fontNameLen-1] != 0 {
@@ -297,28 +592,41 @@ func parseCommonBinary(brd *binary.Reader, blockLength int) (*Common, error) {
 	if !brd.ReadUInt8((*int)(&common.BlueChannel)) {
 		return nil, fmt.Errorf("expected one byte for blueChnl")
 	}
+	if err := checkChannels(common); err != nil {
+		return nil, err
+	}
 
 	return &common, nil
 }
 
 // All spaces at the start or end of a page file name are trimmed
-func parsePagesBinary(brd *binary.Reader, blockLength int) ([]Page, error) {
+func parsePagesBinary(brd *binary.Reader, blockLength int, opts ParseOptions) ([]Page, error) {
+	if blockLength == 0 {
+		return nil, nil
+	}
+
 	var file0 string
 	if !brd.ReadNullString(&file0, blockLength) {
 		return nil, fmt.Errorf("expected first null-terminated pageName within %d bytes", blockLength)
 	}
 
 	nameLen := len(file0) + 1
-	pages := []Page{{
-		Id:   0,
-		File: strings.Trim(file0, " "),
-	}}
-
 	if blockLength%nameLen != 0 {
 		return nil, fmt.Errorf("expected a multiple of %d bytes for pages block but was %d", nameLen, blockLength)
 	}
 
-	for i := 1; i < blockLength/nameLen; i++ {
+	// all names have the same length, so the count is known before the pages are allocated
+	count := blockLength / nameLen
+	if err := opts.checkCounts(0, 0, count); err != nil {
+		return nil, err
+	}
+	pages := make([]Page, 1, count)
+	pages[0] = Page{
+		Id:   0,
+		File: strings.Trim(file0, " "),
+	}
+
+	for i := 1; i < count; i++ {
 		var name string
 
 		if !brd.ReadString(&name, nameLen) {
@@ -350,7 +658,7 @@ func parseCharsBinary(brd *binary.Reader, blockLength int) (chars []Char, err er
 
 	for ; charIdx < charCount; charIdx++ {
 		char := Char{}
-		if !brd.ReadRune(&char.Id) {
+		if !brd.ReadInt32(&char.Id) {
 			return nil, fmt.Errorf("expected four bytes for id")
 		}
 		if !brd.ReadUInt16(&char.X) {
@@ -380,6 +688,9 @@ func parseCharsBinary(brd *binary.Reader, blockLength int) (chars []Char, err er
 		if !brd.ReadUInt8((*int)(&char.Channel)) {
 			return nil, fmt.Errorf("expected one byte for chnl")
 		}
+		if err := checkChar(char); err != nil {
+			return nil, err
+		}
 		chars[charIdx] = char
 	}
 
@@ -403,10 +714,10 @@ func parseKerningPairsBinary(brd *binary.Reader, blockLength int) (kernings []Ke
 
 	for ; kernIdx < kernCount; kernIdx++ {
 		kern := Kerning{}
-		if !brd.ReadRune(&kern.First) {
+		if !brd.ReadInt32(&kern.First) {
 			return nil, fmt.Errorf("expected four bytes for first")
 		}
-		if !brd.ReadRune(&kern.Second) {
+		if !brd.ReadInt32(&kern.Second) {
 			return nil, fmt.Errorf("expected four bytes for second")
 		}
 		if !brd.ReadInt16(&kern.Amount) {
@@ -420,6 +731,12 @@ func parseKerningPairsBinary(brd *binary.Reader, blockLength int) (kernings []Ke
 
 // SerializeBinary serializes a bmf font definition in binary format.
 func SerializeBinary(fnt *Font, dst io.Writer) error {
+	return SerializeOptions{}.SerializeBinary(fnt, dst)
+}
+
+// SerializeBinary serializes a bmf font definition in binary format.
+func (opts SerializeOptions) SerializeBinary(fnt *Font, dst io.Writer) error {
+	fnt = opts.prepare(fnt)
 	bw := &binary.Writer{
 		Order: encoding.LittleEndian,
 		Dst:   dst,
@@ -428,23 +745,23 @@ func SerializeBinary(fnt *Font, dst io.Writer) error {
 	bw.WriteUInt8(SupportedVersion)
 
 	serializeInfoBlockBinary(fnt, bw)
-	if bw.Err != nil {
+	if !bw.Flush() {
 		return bw.Err
 	}
 	serializeCommonBlockBinary(fnt, bw)
-	if bw.Err != nil {
+	if !bw.Flush() {
 		return bw.Err
 	}
 	serializePagesBlockBinary(fnt, bw)
-	if bw.Err != nil {
+	if !bw.Flush() {
 		return bw.Err
 	}
 	serializeCharsBlockBinary(fnt, bw)
-	if bw.Err != nil {
+	if !bw.Flush() {
 		return bw.Err
 	}
 	serializeKerningsBlockBinary(fnt, bw)
-	if bw.Err != nil {
+	if !bw.Flush() {
 		return bw.Err
 	}
 
@@ -453,7 +770,7 @@ func SerializeBinary(fnt *Font, dst io.Writer) error {
 
 func serializeInfoBlockBinary(fnt *Font, bw *binary.Writer) {
 	i := fnt.Info
-	bw.WriteUInt8(uint8(blockInfo))
+	bw.WriteUInt8(uint8(BlockInfo))
 	bw.WriteInt32(14 + int32(len(i.Face)) + 1)
 
 	bw.WriteInt16(int16(i.Size))
@@ -465,10 +782,10 @@ func serializeInfoBlockBinary(fnt *Font, bw *binary.Writer) {
 	flags |= i.Bold.Byte() << 4
 	bw.WriteBits(flags)
 
-	if charSet, found := LookupCharsetValue(i.Charset); found {
+	if charSet, found := i.Charset.lookup(); found {
 		bw.WriteUInt8(uint8(charSet))
 	} else {
-		bw.WriteUInt8(0)
+		bw.WriteUInt8(i.Charset.Value)
 	}
 
 	bw.WriteUInt16(uint16(i.StretchH))
@@ -489,7 +806,7 @@ func serializeInfoBlockBinary(fnt *Font, bw *binary.Writer) {
 
 func serializeCommonBlockBinary(fnt *Font, bw *binary.Writer) {
 	c := fnt.Common
-	bw.WriteUInt8(uint8(blockCommon))
+	bw.WriteUInt8(uint8(BlockCommon))
 	bw.WriteInt32(15)
 
 	bw.WriteInt16(int16(c.LineHeight))
@@ -520,7 +837,7 @@ func serializePagesBlockBinary(fnt *Font, bw *binary.Writer) {
 		}
 	}
 
-	bw.WriteUInt8(uint8(blockPages))
+	bw.WriteUInt8(uint8(BlockPages))
 	bw.WriteInt32((nameLen + 1) * int32(len(fnt.Pages)))
 
 	for _, p := range fnt.Pages {
@@ -529,7 +846,7 @@ func serializePagesBlockBinary(fnt *Font, bw *binary.Writer) {
 }
 
 func serializeCharsBlockBinary(fnt *Font, bw *binary.Writer) {
-	bw.WriteUInt8(uint8(blockChars))
+	bw.WriteUInt8(uint8(BlockChars))
 	bw.WriteInt32(20 * int32(len(fnt.Chars)))
 
 	for _, c := range fnt.Chars {
@@ -552,7 +869,7 @@ func serializeCharsBlockBinary(fnt *Font, bw *binary.Writer) {
 }
 
 func serializeKerningsBlockBinary(fnt *Font, bw *binary.Writer) {
-	bw.WriteUInt8(uint8(blockKerningPairs))
+	bw.WriteUInt8(uint8(BlockKerningPairs))
 	bw.WriteInt32(10 * int32(len(fnt.Kernings)))
 
 	for _, k := range fnt.Kernings {
@@ -561,3 +878,23 @@ func serializeKerningsBlockBinary(fnt *Font, bw *binary.Writer) {
 		bw.WriteInt16(int16(k.Amount))
 	}
 }
+
+// MarshalBinary implements encoding.BinaryMarshaler using the binary format.
+// Font.DistanceField is not stored, gob uses GobEncode instead to keep it.
+func (font Font) MarshalBinary() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := SerializeBinary(&font, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler using the binary format
+func (font *Font) UnmarshalBinary(data []byte) error {
+	fnt, err := ParseBinaryBytes(data)
+	if err != nil {
+		return err
+	}
+	*font = *fnt
+	return nil
+}
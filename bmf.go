@@ -5,8 +5,9 @@ package bmf
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
-	"strconv"
 )
 
 // BinBool represents a boolean as 0 or 1
@@ -39,93 +40,95 @@ const (
 // Padding specifies the padding for each character in pixels
 // See https://www.angelcode.com/products/bmfont/doc/export_options.html
 type Padding struct {
-	Up    int
-	Right int
-	Down  int
-	Left  int
+	Up    int `json:"up"`
+	Right int `json:"right"`
+	Down  int `json:"down"`
+	Left  int `json:"left"`
 }
 
 // Spacing specifies the spacing for each character in pixels
 // See https://www.angelcode.com/products/bmfont/doc/export_options.html
 type Spacing struct {
-	Horizontal int
-	Vertical   int
+	Horizontal int `json:"horizontal"`
+	Vertical   int `json:"vertical"`
 }
 
 // Font defines an AngelCode Bitmap Font
 type Font struct {
-	Info     Info      `xml:"info"`
-	Common   Common    `xml:"common"`
-	Pages    []Page    `xml:"pages>page"`
-	Chars    []Char    `xml:"chars>char"`
-	Kernings []Kerning `xml:"kernings>kerning"`
+	Info          Info           `xml:"info" json:"info"`
+	Common        Common         `xml:"common" json:"common"`
+	DistanceField *DistanceField `xml:"distanceField" json:"distanceField,omitempty"`
+	Pages         []Page         `xml:"pages>page" json:"pages"`
+	Chars         []Char         `xml:"chars>char" json:"chars"`
+	Kernings      []Kerning      `xml:"kernings>kerning" json:"kernings"`
 }
 
 // Info holds information on how the font was generated
 type Info struct {
-	Face     string  `xml:"face,attr"`
-	Size     int     `xml:"size,attr"`
-	Bold     BinBool `xml:"bold,attr"`
-	Italic   BinBool `xml:"italic,attr"`
-	Charset  string  `xml:"charset,attr"`
-	Unicode  BinBool `xml:"unicode,attr"`
-	StretchH int     `xml:"stretchH,attr"`
-	Smooth   BinBool `xml:"smooth,attr"`
-	AA       int     `xml:"aa,attr"`
-	Padding  Padding `xml:"padding,attr"`
-	Spacing  Spacing `xml:"spacing,attr"`
-	Outline  int     `xml:"outline,attr"`
+	Face     string  `xml:"face,attr" json:"face"`
+	Size     int     `xml:"size,attr" json:"size"`
+	Bold     BinBool `xml:"bold,attr" json:"bold"`
+	Italic   BinBool `xml:"italic,attr" json:"italic"`
+	Charset  Charset `xml:"charset,attr" json:"charset"`
+	Unicode  BinBool `xml:"unicode,attr" json:"unicode"`
+	StretchH int     `xml:"stretchH,attr" json:"stretchH"`
+	Smooth   BinBool `xml:"smooth,attr" json:"smooth"`
+	AA       int     `xml:"aa,attr" json:"aa"`
+	Padding  Padding `xml:"padding,attr" json:"padding"`
+	Spacing  Spacing `xml:"spacing,attr" json:"spacing"`
+	Outline  int     `xml:"outline,attr" json:"outline"`
 }
 
 // Common holds information common to all characters.
 type Common struct {
-	LineHeight   int         `xml:"lineHeight,attr"`
-	Base         int         `xml:"base,attr"`
-	ScaleW       int         `xml:"scaleW,attr"`
-	ScaleH       int         `xml:"scaleH,attr"`
-	Pages        int         `xml:"pages,attr"`
-	Packed       BinBool     `xml:"packed,attr"`
-	AlphaChannel ChannelData `xml:"alphaChnl,attr"`
-	RedChannel   ChannelData `xml:"redChnl,attr"`
-	GreenChannel ChannelData `xml:"greenChnl,attr"`
-	BlueChannel  ChannelData `xml:"blueChnl,attr"`
+	LineHeight   int         `xml:"lineHeight,attr" json:"lineHeight"`
+	Base         int         `xml:"base,attr" json:"base"`
+	ScaleW       int         `xml:"scaleW,attr" json:"scaleW"`
+	ScaleH       int         `xml:"scaleH,attr" json:"scaleH"`
+	Pages        int         `xml:"pages,attr" json:"pages"`
+	Packed       BinBool     `xml:"packed,attr" json:"packed"`
+	AlphaChannel ChannelData `xml:"alphaChnl,attr" json:"alphaChnl"`
+	RedChannel   ChannelData `xml:"redChnl,attr" json:"redChnl"`
+	GreenChannel ChannelData `xml:"greenChnl,attr" json:"greenChnl"`
+	BlueChannel  ChannelData `xml:"blueChnl,attr" json:"blueChnl"`
+}
+
+// DistanceField marks fonts whose pages hold distance fields instead of glyph coverage.
+// It is not part of the AngelCode format but an extension of distance field generators like msdf-bmfont.
+// It is only stored in the text and XML formats, the binary format has no block for it.
+type DistanceField struct {
+	// FieldType is "sdf" for signed distance fields
+	FieldType string `xml:"fieldType,attr" json:"fieldType"`
+	// DistanceRange is the distance in pixels that the values from 0 to 255 span
+	DistanceRange int `xml:"distanceRange,attr" json:"distanceRange"`
 }
 
 // Char describes on character in the font. There is one for each included character in the font.
 type Char struct {
-	Id       rune    `xml:"id,attr"`
-	X        int     `xml:"x,attr"`
-	Y        int     `xml:"y,attr"`
-	Width    int     `xml:"width,attr"`
-	Height   int     `xml:"height,attr"`
-	XOffset  int     `xml:"xoffset,attr"`
-	YOffset  int     `xml:"yoffset,attr"`
-	XAdvance int     `xml:"xadvance,attr"`
-	Page     int     `xml:"page,attr"`
-	Channel  Channel `xml:"chnl,attr"`
+	Id       rune    `xml:"id,attr" json:"id"`
+	X        int     `xml:"x,attr" json:"x"`
+	Y        int     `xml:"y,attr" json:"y"`
+	Width    int     `xml:"width,attr" json:"width"`
+	Height   int     `xml:"height,attr" json:"height"`
+	XOffset  int     `xml:"xoffset,attr" json:"xoffset"`
+	YOffset  int     `xml:"yoffset,attr" json:"yoffset"`
+	XAdvance int     `xml:"xadvance,attr" json:"xadvance"`
+	Page     int     `xml:"page,attr" json:"page"`
+	Channel  Channel `xml:"chnl,attr" json:"chnl"`
 }
 
 // Kerning specifies the distance between specific character pairs
 type Kerning struct {
-	First  rune `xml:"first,attr"`
-	Second rune `xml:"second,attr"`
-	Amount int  `xml:"amount,attr"`
+	First  rune `xml:"first,attr" json:"first"`
+	Second rune `xml:"second,attr" json:"second"`
+	Amount int  `xml:"amount,attr" json:"amount"`
 }
 
 // Page references a bitmap image that contains the glyphs.
 // A font can contain multiple glyph pages.
 type Page struct {
-	Id   int    `xml:"id,attr"`
-	File string `xml:"file,attr"`
-}
-
-func atoi(i *int, a string) {
-	v, err := strconv.Atoi(a)
-	if err == nil {
-		*i = v
-	} else {
-		*i = 0
-	}
+	Id   int    `xml:"id,attr" json:"id"`
+	File string `xml:"file,attr" json:"file"`
 }
 
 // Bool converts a number to a BinBool where 1 is true
@@ -141,21 +144,153 @@ func (b BinBool) Byte() byte {
 	return 0
 }
 
-// Parse parses a bmf font file and detects the format automatically
+// Parse parses a bmf font file and detects the format automatically.
+// No limits are applied, use ParseOptions for untrusted input.
 func Parse(src io.Reader) (*Font, error) {
+	return ParseOptions{}.Parse(src)
+}
+
+// Parse parses a bmf font file and detects the format automatically
+func (opts ParseOptions) Parse(src io.Reader) (*Font, error) {
+	return opts.ParseContext(context.Background(), src)
+}
+
+// ParseContext parses a bmf font file, detects the format automatically and stops once ctx is done.
+// The error of ctx is returned wrapped in a TextParseError or BinaryParseError,
+// use errors.Is to tell it apart from format errors.
+// When ctx is done before the format is detected, text is assumed.
+func ParseContext(ctx context.Context, src io.Reader) (*Font, error) {
+	return ParseOptions{}.ParseContext(ctx, src)
+}
+
+// ParseContext parses a bmf font file, detects the format automatically and stops once ctx is done.
+// The error of ctx is returned wrapped in a TextParseError or BinaryParseError,
+// use errors.Is to tell it apart from format errors.
+// When ctx is done before the format is detected, text is assumed.
+func (opts ParseOptions) ParseContext(ctx context.Context, src io.Reader) (*Font, error) {
+	start, src, err := peek(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	if isBinary(start) {
+		return opts.ParseBinaryContext(ctx, src)
+	}
+	if isXML(start) {
+		return opts.ParseXMLContext(ctx, src)
+	}
+	return opts.ParseTextContext(ctx, src)
+}
+
+// peek reads the start of src to detect the format.
+// It returns the start along with a reader that still includes it.
+func peek(ctx context.Context, src io.Reader) ([]byte, io.Reader, error) {
 	start := make([]byte, 5)
 
-	if _, err := src.Read(start); err != nil {
+	n, err := io.ReadFull(withContext(ctx, src), start)
+	// when ctx is done, the parser of the detected format fails with the wrapped error
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && ctx.Err() == nil {
+		return nil, nil, err
+	}
+	start = start[:n]
+
+	return start, io.MultiReader(bytes.NewReader(start), src), nil
+}
+
+// ParseAll parses a file with several bmf fonts and detects the format automatically.
+// In the text format each info line starts a new font, in the XML format each font element is a font,
+// also when they are nested in another element. A binary file holds a single font.
+// The limits of ParseOptions apply to each font, while MaxInputSize limits the whole file.
+func ParseAll(src io.Reader) ([]*Font, error) {
+	return ParseOptions{}.ParseAll(src)
+}
+
+// ParseAll parses a file with several bmf fonts and detects the format automatically.
+// In the text format each info line starts a new font, in the XML format each font element is a font,
+// also when they are nested in another element. A binary file holds a single font.
+// The limits of ParseOptions apply to each font, while MaxInputSize limits the whole file.
+func (opts ParseOptions) ParseAll(src io.Reader) ([]*Font, error) {
+	start, src, err := peek(context.Background(), src)
+	if err != nil {
 		return nil, err
 	}
 
-	src = io.MultiReader(bytes.NewBufferString(string(start)), src)
+	if isBinary(start) {
+		fnt, err := opts.ParseBinary(src)
+		if err != nil {
+			return nil, err
+		}
+		return []*Font{fnt}, nil
+	}
+	if isXML(start) {
+		return opts.parseAllXML(src)
+	}
 
-	if string(start[:3]) == "BMF" {
-		return ParseBinary(src)
+	p := &textParser{ctx: context.Background(), opts: opts, fnt: &Font{}, split: true}
+	if err := p.parse(src); err != nil {
+		return nil, err
 	}
-	if string(start[:5]) == "<?xml" {
-		return ParseXML(src)
+	fnt, err := p.finish()
+	if err != nil {
+		return nil, err
 	}
-	return ParseText(src)
+	return append(p.fonts, fnt), nil
+}
+
+// ParseBytes parses a bmf font file and detects the format automatically.
+// The data is parsed in place, which makes it suitable for memory-mapped files.
+func ParseBytes(data []byte) (*Font, error) {
+	return ParseOptions{}.ParseBytes(data)
+}
+
+// ParseBytes parses a bmf font file and detects the format automatically.
+// The data is parsed in place, which makes it suitable for memory-mapped files.
+func (opts ParseOptions) ParseBytes(data []byte) (*Font, error) {
+	if isBinary(data) {
+		return opts.ParseBinaryBytes(data)
+	}
+	if isXML(data) {
+		return opts.ParseXMLBytes(data)
+	}
+	return opts.ParseTextBytes(data)
+}
+
+// ParseReaderAt parses a bmf font file of the given size in bytes and detects the format automatically.
+// Binary fonts are read block by block, see OpenBinary.
+func ParseReaderAt(src io.ReaderAt, size int64) (*Font, error) {
+	return ParseOptions{}.ParseReaderAt(src, size)
+}
+
+// ParseReaderAt parses a bmf font file of the given size in bytes and detects the format automatically.
+// Binary fonts are read block by block, see OpenBinary.
+func (opts ParseOptions) ParseReaderAt(src io.ReaderAt, size int64) (*Font, error) {
+	// nothing past size is read, also not to detect the format
+	section := io.NewSectionReader(src, 0, size)
+	start := make([]byte, 5)
+
+	n, err := section.ReadAt(start, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	start = start[:n]
+
+	if isBinary(start) {
+		bb, err := opts.OpenBinary(section, size)
+		if err != nil {
+			return nil, err
+		}
+		return bb.Font()
+	}
+	if isXML(start) {
+		return opts.ParseXML(section)
+	}
+	return opts.ParseText(section)
+}
+
+func isBinary(start []byte) bool {
+	return bytes.HasPrefix(start, []byte("BMF"))
+}
+
+func isXML(start []byte) bool {
+	return bytes.HasPrefix(start, []byte("<?xml"))
 }
@@ -2,14 +2,31 @@ package bmf_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
 	"io/ioutil"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/Qendolin/go-bmf"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
 )
 
 var Expected = bmf.Font{
@@ -18,7 +35,7 @@ var Expected = bmf.Font{
 		Size:     -26,
 		Bold:     true,
 		Italic:   true,
-		Charset:  "",
+		Charset:  bmf.Charset{},
 		Unicode:  true,
 		StretchH: 90,
 		Smooth:   true,
@@ -127,6 +144,36 @@ func TestParseBinary(t *testing.T) {
 	assertFontEqual(t, Expected, *fnt)
 }
 
+func TestParseBinaryShortReads(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/test-bin.fnt")
+	require.NoErrorf(t, err, "Unable to read testdata")
+
+	fnt, err := bmf.ParseBinary(iotest.OneByteReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	assertFontEqual(t, Expected, *fnt)
+
+	fnt, err = bmf.ParseBinary(iotest.DataErrReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	assertFontEqual(t, Expected, *fnt)
+
+	_, err = bmf.ParseBinary(bytes.NewReader(data[:len(data)-3]))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestParseBinaryForged(t *testing.T) {
+	// chars block claiming 4 GB of data
+	data := []byte{'B', 'M', 'F', 3, 4, 0xf0, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+	_, err := bmf.ParseBinary(bytes.NewReader(data))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	_, err = bmf.ParseBinaryBytes(data)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	// empty pages block
+	fnt, err := bmf.ParseBinaryBytes([]byte{'B', 'M', 'F', 3, 3, 0, 0, 0, 0})
+	require.NoError(t, err)
+	assert.Empty(t, fnt.Pages)
+}
+
 func TestWriteBinary(t *testing.T) {
 	data := &bytes.Buffer{}
 	err := bmf.SerializeBinary(&Expected, data)
@@ -155,6 +202,42 @@ func TestWriteText(t *testing.T) {
 	assertFontEqual(t, Expected, *fnt)
 }
 
+func TestSerializeXML(t *testing.T) {
+	data := &bytes.Buffer{}
+	require.NoError(t, bmf.SerializeXML(&Expected, data))
+	fnt, err := bmf.Parse(data)
+	require.NoError(t, err)
+	assertFontEqual(t, Expected, *fnt)
+}
+
+func TestNormalize(t *testing.T) {
+	shuffled := Expected
+	shuffled.Pages = []bmf.Page{{Id: 7, File: "b.png"}, {Id: 3, File: "a.png"}}
+	shuffled.Chars = []bmf.Char{{Id: 'b', Page: 7}, {Id: 'a', Page: 3}, {Id: 'b', Page: 3, XAdvance: 1}}
+	shuffled.Kernings = []bmf.Kerning{{First: 'b', Second: 'a'}, {First: 'a', Second: 'b', Amount: 1}, {First: 'a', Second: 'b', Amount: 2}}
+
+	norm := bmf.Normalize(&shuffled)
+	assert.Equal(t, []bmf.Page{{Id: 0, File: "a.png"}, {Id: 1, File: "b.png"}}, norm.Pages)
+	assert.Equal(t, []bmf.Char{{Id: 'a', Page: 0}, {Id: 'b', Page: 0, XAdvance: 1}}, norm.Chars)
+	assert.Equal(t, []bmf.Kerning{{First: 'a', Second: 'b', Amount: 2}, {First: 'b', Second: 'a'}}, norm.Kernings)
+	assert.Equal(t, 2, norm.Common.Pages)
+	assert.Equal(t, rune('b'), shuffled.Chars[0].Id, "the original is not modified")
+
+	reversed := Expected
+	reversed.Chars = nil
+	for i := len(Expected.Chars) - 1; i >= 0; i-- {
+		reversed.Chars = append(reversed.Chars, Expected.Chars[i])
+	}
+
+	canonical := bmf.SerializeOptions{Canonical: true}
+	for _, serialize := range []func(*bmf.Font, io.Writer) error{canonical.SerializeText, canonical.SerializeBinary, canonical.SerializeXML} {
+		a, b := &bytes.Buffer{}, &bytes.Buffer{}
+		require.NoError(t, serialize(&Expected, a))
+		require.NoError(t, serialize(&reversed, b))
+		assert.Equal(t, a.String(), b.String())
+	}
+}
+
 func TestAutoDetect(t *testing.T) {
 
 	parse := func(file string) {
@@ -172,6 +255,1164 @@ func TestAutoDetect(t *testing.T) {
 	parse("./testdata/test-xml.fnt")
 }
 
+func TestParseBytes(t *testing.T) {
+	for _, file := range []string{"./testdata/test-text.fnt", "./testdata/test-bin.fnt", "./testdata/test-xml.fnt"} {
+		data, err := ioutil.ReadFile(file)
+		require.NoErrorf(t, err, "Unable to read testdata")
+
+		fnt, err := bmf.ParseBytes(data)
+		require.NoError(t, err, file)
+		assertFontEqual(t, Expected, *fnt)
+
+		fnt, err = bmf.ParseReaderAt(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err, file)
+		assertFontEqual(t, Expected, *fnt)
+
+		// the format is detected from the first size bytes only
+		for _, size := range []int64{0, 2} {
+			_, _ = bmf.ParseReaderAt(sizedReaderAt{t: t, data: data, size: size}, size)
+		}
+	}
+
+	fnt, err := bmf.ParseReaderAt(bytes.NewReader([]byte("BMF\x03")), 0)
+	require.NoError(t, err)
+	assert.Equal(t, &bmf.Font{}, fnt)
+}
+
+// sizedReaderAt fails the test when it is read past size
+type sizedReaderAt struct {
+	t    *testing.T
+	data []byte
+	size int64
+}
+
+func (r sizedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > r.size {
+		r.t.Errorf("read of %d bytes at %d is past size %d", len(p), off, r.size)
+	}
+	return bytes.NewReader(r.data).ReadAt(p, off)
+}
+
+func TestOpenBinary(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/test-bin.fnt")
+	require.NoErrorf(t, err, "Unable to read testdata")
+
+	bb, err := bmf.OpenBinary(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	chars, err := bb.Chars()
+	require.NoError(t, err)
+	assert.Equal(t, Expected.Chars, chars)
+	kernings, err := bb.Kernings()
+	require.NoError(t, err)
+	assert.Equal(t, Expected.Kernings, kernings)
+
+	_, err = bmf.OpenBinary(bytes.NewReader(data), int64(len(data)-3))
+	assert.IsType(t, bmf.BinaryParseError{}, err)
+}
+
+func TestParseOptions(t *testing.T) {
+	limits := map[string]bmf.ParseOptions{
+		"MaxChars":        {MaxChars: 3},
+		"MaxKernings":     {MaxKernings: 3},
+		"MaxPages":        {MaxPages: 1},
+		"MaxStringLength": {MaxStringLength: 4},
+		"MaxInputSize":    {MaxInputSize: 100},
+	}
+
+	for _, file := range []string{"./testdata/test-text.fnt", "./testdata/test-bin.fnt", "./testdata/test-xml.fnt"} {
+		data, err := ioutil.ReadFile(file)
+		require.NoErrorf(t, err, "Unable to read testdata")
+
+		for limit, opts := range limits {
+			var limitErr bmf.LimitError
+			_, err = opts.Parse(bytes.NewReader(data))
+			if assert.Truef(t, errors.As(err, &limitErr), "%v %v: %v", file, limit, err) {
+				assert.Equal(t, limit, limitErr.Limit)
+			}
+			_, err = opts.ParseBytes(data)
+			if assert.Truef(t, errors.As(err, &limitErr), "%v %v: %v", file, limit, err) {
+				assert.Equal(t, limit, limitErr.Limit)
+			}
+		}
+
+		fnt, err := bmf.ParseOptions{MaxChars: 4, MaxKernings: 4, MaxPages: 2, MaxStringLength: 14, MaxInputSize: len(data)}.ParseBytes(data)
+		require.NoError(t, err, file)
+		assertFontEqual(t, Expected, *fnt)
+	}
+
+	// the XML limits stop decoding at the first element over the limit, before the invalid ones
+	data, err := ioutil.ReadFile("./testdata/test-xml.fnt")
+	require.NoErrorf(t, err, "Unable to read testdata")
+	xmlLimits := []struct {
+		limit   string
+		opts    bmf.ParseOptions
+		end     string
+		invalid string
+	}{
+		{"MaxPages", bmf.ParseOptions{MaxPages: 1}, "</pages>", `<page id="x"/>`},
+		{"MaxChars", bmf.ParseOptions{MaxChars: 3}, "</chars>", `<char id="x"/>`},
+		{"MaxKernings", bmf.ParseOptions{MaxKernings: 3}, "</kernings>", `<kerning first="x"/>`},
+	}
+	for _, c := range xmlLimits {
+		invalid := strings.Replace(string(data), c.end, c.invalid+c.end, 1)
+		_, err = bmf.ParseXMLBytes([]byte(invalid))
+		require.Error(t, err)
+
+		var limitErr bmf.LimitError
+		_, err = c.opts.ParseXML(strings.NewReader(invalid))
+		if assert.Truef(t, errors.As(err, &limitErr), "%v: %v", c.limit, err) {
+			assert.Equal(t, c.limit, limitErr.Limit)
+		}
+	}
+
+	// a pages block of empty names is rejected before the pages are allocated
+	forged := make([]byte, 9+4<<20)
+	copy(forged, "BMF\x03\x03")
+	binary.LittleEndian.PutUint32(forged[5:], 4<<20)
+	pagesOpts := bmf.ParseOptions{MaxPages: 10}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = pagesOpts.ParseBinaryBytes(forged)
+	runtime.ReadMemStats(&after)
+	var pagesErr bmf.LimitError
+	if assert.True(t, errors.As(err, &pagesErr), err) {
+		assert.Equal(t, "MaxPages", pagesErr.Limit)
+	}
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+	_, err = pagesOpts.ParseBinary(bytes.NewReader(forged))
+	assert.True(t, errors.As(err, &pagesErr), err)
+	bb, err := pagesOpts.OpenBinary(bytes.NewReader(forged), int64(len(forged)))
+	require.NoError(t, err)
+	_, err = bb.Pages()
+	assert.True(t, errors.As(err, &pagesErr), err)
+
+	var limitErr bmf.LimitError
+	_, err = bmf.ParseOptions{MaxLineLength: 100}.ParseText(bytes.NewBufferString(strings.Repeat("info ", 30)))
+	assert.True(t, errors.As(err, &limitErr))
+	_, err = bmf.ParseOptions{MaxLineLength: 100}.ParseTextBytes([]byte(strings.Repeat("info ", 30)))
+	assert.True(t, errors.As(err, &limitErr))
+}
+
+func TestParseTextLineEndings(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/test-text.fnt")
+	require.NoErrorf(t, err, "Unable to read testdata")
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+
+	endings := map[string]func(i int) string{
+		"crlf":  func(int) string { return "\r\n" },
+		"cr":    func(int) string { return "\r" },
+		"mixed": func(i int) string { return []string{"\n", "\r\n", "\r"}[i%3] },
+	}
+	for name, ending := range endings {
+		var sb strings.Builder
+		for i, line := range lines {
+			sb.WriteString(line)
+			sb.WriteString(ending(i))
+		}
+		text := sb.String()
+
+		fnt, err := bmf.ParseText(strings.NewReader(text))
+		require.NoError(t, err, name)
+		assertFontEqual(t, Expected, *fnt)
+		// a "\r" at the end of a read may be followed by a "\n" in the next one
+		fnt, err = bmf.ParseText(iotest.OneByteReader(strings.NewReader(text)))
+		require.NoError(t, err, name)
+		assertFontEqual(t, Expected, *fnt)
+		fnt, err = bmf.ParseTextBytes([]byte(text))
+		require.NoError(t, err, name)
+		assertFontEqual(t, Expected, *fnt)
+	}
+
+	// lines are not limited by the buffer size of bufio.Scanner
+	face := strings.Repeat("a", 1<<20)
+	fnt, err := bmf.ParseText(strings.NewReader("common lineHeight=27\r\ninfo face=\"" + face + "\" size=-26\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, face, fnt.Info.Face)
+	assert.Equal(t, -26, fnt.Info.Size)
+	assert.Equal(t, 27, fnt.Common.LineHeight)
+
+	// the line ending does not count towards MaxLineLength
+	opts := bmf.ParseOptions{MaxLineLength: 20}
+	for _, ending := range []string{"\n", "\r\n", "\r", ""} {
+		line := "info face=\"" + strings.Repeat("a", 8) + "\""
+		require.Len(t, line, 20)
+		_, err = opts.ParseText(strings.NewReader(line + ending))
+		assert.NoErrorf(t, err, "%q", ending)
+		_, err = opts.ParseTextBytes([]byte(line + ending))
+		assert.NoErrorf(t, err, "%q", ending)
+
+		var textErr bmf.TextParseError
+		_, err = opts.ParseText(strings.NewReader("common base=1\n" + line + " " + ending))
+		if assert.Truef(t, errors.As(err, &textErr), "%q", ending) {
+			assert.Equal(t, 2, textErr.LineNumber)
+			assert.Contains(t, textErr.Error(), "limit MaxLineLength of 20 exceeded")
+		}
+		_, err = opts.ParseTextBytes([]byte("common base=1\n" + line + " " + ending))
+		if assert.Truef(t, errors.As(err, &textErr), "%q", ending) {
+			assert.Equal(t, 2, textErr.LineNumber)
+		}
+	}
+
+	// the message only includes the start of long lines
+	_, err = bmf.ParseText(strings.NewReader("info face=\"" + face))
+	require.Error(t, err)
+	assert.Less(t, len(err.Error()), 200)
+}
+
+func TestTruncated(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/test-text.fnt")
+	require.NoErrorf(t, err, "Unable to read testdata")
+	lines := strings.SplitAfter(string(data), "\n")
+
+	cases := []struct {
+		text     string
+		block    string
+		lineNr   int
+		expected int
+		actual   int
+	}{
+		// cut off in the chars, so the kernings line is missing
+		{strings.Join(lines[:7], ""), "chars", 5, 4, 2},
+		{strings.Join(lines[:12], ""), "kernings", 10, 4, 2},
+		{strings.Join(lines[:13], ""), "kernings", 10, 4, 3},
+	}
+	for _, c := range cases {
+		for name, parse := range map[string]func(string) (*bmf.Font, error){
+			"reader": func(s string) (*bmf.Font, error) { return bmf.ParseText(strings.NewReader(s)) },
+			"bytes":  func(s string) (*bmf.Font, error) { return bmf.ParseTextBytes([]byte(s)) },
+		} {
+			_, err := parse(c.text)
+			var textErr bmf.TextParseError
+			var truncErr bmf.TruncatedError
+			if assert.Truef(t, errors.As(err, &textErr), "%v: %v", name, err) {
+				assert.Equal(t, c.lineNr, textErr.LineNumber)
+			}
+			if assert.Truef(t, errors.As(err, &truncErr), "%v: %v", name, err) {
+				assert.Equal(t, bmf.TruncatedError{Block: c.block, Expected: c.expected, Actual: c.actual}, truncErr)
+			}
+		}
+	}
+
+	// invalid counts are ignored by both formats, like missing ones
+	for _, count := range []string{"", `""`, "x", "4x"} {
+		text := strings.Replace(strings.Join(lines[:7], ""), "chars count=4", "chars count="+count, 1)
+		_, err := bmf.ParseText(strings.NewReader(text))
+		assert.NoErrorf(t, err, "%q", count)
+	}
+
+	// more records than the count and missing counts are accepted
+	text := strings.Replace(string(data), "chars count=4", "chars count=3", 1)
+	text = strings.Replace(text, "kernings count=4\n", "", 1)
+	fnt, err := bmf.ParseText(strings.NewReader(text))
+	require.NoError(t, err)
+	assertFontEqual(t, Expected, *fnt)
+
+	// the XML format writes and checks the count attributes
+	buf := &bytes.Buffer{}
+	require.NoError(t, bmf.SerializeXML(&Expected, buf))
+	assert.Contains(t, buf.String(), `<chars count="4">`)
+	assert.Contains(t, buf.String(), `<kernings count="4">`)
+
+	xmlData, err := ioutil.ReadFile("./testdata/test-xml.fnt")
+	require.NoErrorf(t, err, "Unable to read testdata")
+	xmlLines := strings.SplitAfter(string(xmlData), "\n")
+	truncated := strings.Join(append(xmlLines[:18:18], xmlLines[19:]...), "")
+	var truncErr bmf.TruncatedError
+	_, err = bmf.ParseXML(strings.NewReader(truncated))
+	if assert.True(t, errors.As(err, &truncErr), err) {
+		assert.Equal(t, bmf.TruncatedError{Block: "kernings", Expected: 4, Actual: 3}, truncErr)
+	}
+	truncated = strings.Join(append(xmlLines[:10:10], xmlLines[11:]...), "")
+	_, err = bmf.ParseXMLBytes([]byte(truncated))
+	if assert.True(t, errors.As(err, &truncErr), err) {
+		assert.Equal(t, bmf.TruncatedError{Block: "chars", Expected: 4, Actual: 3}, truncErr)
+	}
+	for _, count := range []string{"", "x", " 4x"} {
+		_, err = bmf.ParseXMLBytes([]byte(strings.Replace(truncated, `<chars count="4">`, `<chars count="`+count+`">`, 1)))
+		assert.NoErrorf(t, err, "%q", count)
+	}
+
+	// fonts without kerning pairs have no kernings element
+	noKernings := Expected
+	noKernings.Kernings = nil
+	buf.Reset()
+	require.NoError(t, bmf.SerializeXML(&noKernings, buf))
+	assert.NotContains(t, buf.String(), "kernings")
+	fnt, err = bmf.ParseXML(buf)
+	require.NoError(t, err)
+	assert.Empty(t, fnt.Kernings)
+}
+
+func TestParseAll(t *testing.T) {
+	for _, file := range []string{"./testdata/test-text.fnt", "./testdata/test-bin.fnt", "./testdata/test-xml.fnt"} {
+		data, err := ioutil.ReadFile(file)
+		require.NoErrorf(t, err, "Unable to read testdata")
+		fonts, err := bmf.ParseAll(bytes.NewReader(data))
+		require.NoError(t, err, file)
+		require.Len(t, fonts, 1, file)
+		assertFontEqual(t, Expected, *fonts[0])
+	}
+
+	second := Expected
+	second.Info.Face = "Second"
+	second.Chars = second.Chars[:2]
+	second.Kernings = nil
+
+	// each info line starts a new font
+	buf := &bytes.Buffer{}
+	require.NoError(t, bmf.SerializeText(&Expected, buf))
+	require.NoError(t, bmf.SerializeText(&second, buf))
+	fonts, err := bmf.ParseAll(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, fonts, 2)
+	assertFontEqual(t, Expected, *fonts[0])
+	assertFontEqual(t, second, *fonts[1])
+
+	// ParseText keeps reading a single font
+	fnt, err := bmf.ParseText(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "Second", fnt.Info.Face)
+	assert.Len(t, fnt.Chars, len(Expected.Chars)+len(second.Chars))
+
+	// the counts are checked for each font
+	truncated := strings.Replace(buf.String(), "chars count=4", "chars count=5", 1)
+	_, err = bmf.ParseAll(strings.NewReader(truncated))
+	var truncErr bmf.TruncatedError
+	assert.True(t, errors.As(err, &truncErr), err)
+
+	// concatenated XML files and font elements in another element
+	buf.Reset()
+	require.NoError(t, bmf.SerializeXML(&Expected, buf))
+	require.NoError(t, bmf.SerializeXML(&second, buf))
+	data, err := xml.Marshal(struct {
+		XMLName xml.Name   `xml:"fonts"`
+		Fonts   []bmf.Font `xml:"font"`
+	}{Fonts: []bmf.Font{Expected, second}})
+	require.NoError(t, err)
+	for _, xmlData := range [][]byte{buf.Bytes(), append([]byte("<?xml version=\"1.0\"?>\n"), data...)} {
+		fonts, err = bmf.ParseAll(bytes.NewReader(xmlData))
+		require.NoError(t, err)
+		require.Len(t, fonts, 2)
+		assertFontEqual(t, Expected, *fonts[0])
+		assertFontEqual(t, second, *fonts[1])
+	}
+
+	_, err = bmf.ParseOptions{MaxChars: 3}.ParseAll(bytes.NewReader(buf.Bytes()))
+	var limitErr bmf.LimitError
+	assert.True(t, errors.As(err, &limitErr), err)
+	_, err = bmf.ParseAll(strings.NewReader("<?xml version=\"1.0\"?><fonts></fonts>"))
+	assert.Error(t, err)
+}
+
+// cancelReader cancels a context after reading n bytes
+type cancelReader struct {
+	src    io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (cr *cancelReader) Read(p []byte) (int, error) {
+	if cr.n <= 0 {
+		cr.cancel()
+	}
+	if len(p) > cr.n && cr.n > 0 {
+		p = p[:cr.n]
+	}
+	n, err := cr.src.Read(p)
+	cr.n -= n
+	return n, err
+}
+
+func TestParseContext(t *testing.T) {
+	files := map[string]bool{
+		"./testdata/test-text.fnt": false,
+		"./testdata/test-bin.fnt":  true,
+		"./testdata/test-xml.fnt":  false,
+	}
+	for file, binary := range files {
+		data, err := ioutil.ReadFile(file)
+		require.NoErrorf(t, err, "Unable to read testdata")
+
+		fnt, err := bmf.ParseContext(context.Background(), bytes.NewReader(data))
+		require.NoError(t, err, file)
+		assertFontEqual(t, Expected, *fnt)
+
+		// canceled before, at the start and in the middle of the input.
+		// Before the format is detected, the error is reported for the text format.
+		for _, n := range []int{-1, 0, 10, len(data) / 2} {
+			ctx, cancel := context.WithCancel(context.Background())
+			if n < 0 {
+				cancel()
+			}
+			_, err = bmf.ParseContext(ctx, &cancelReader{src: bytes.NewReader(data), n: n, cancel: cancel})
+			cancel()
+			assert.Truef(t, errors.Is(err, context.Canceled), "%v %v: %v", file, n, err)
+			if binary && n >= 0 {
+				var binErr bmf.BinaryParseError
+				assert.Truef(t, errors.As(err, &binErr), "%v %v: %v", file, n, err)
+			} else {
+				var textErr bmf.TextParseError
+				assert.Truef(t, errors.As(err, &textErr), "%v %v: %v", file, n, err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := bmf.ParseTextContext(ctx, strings.NewReader("info face=\"a\"\n"))
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, err = bmf.ParseXMLContext(ctx, strings.NewReader("<?xml version=\"1.0\"?><font></font>"))
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, err = bmf.ParseBinaryContext(ctx, strings.NewReader("BMF\x03"))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestCharset(t *testing.T) {
+	r, err := bmf.DecodeCharId(bmf.ParseCharset("ShiftJIS"), 0x82a0)
+	require.NoError(t, err)
+	assert.Equal(t, 'あ', r)
+	id, err := bmf.EncodeCharId(bmf.ParseCharset("shiftjis"), 'あ')
+	require.NoError(t, err)
+	assert.Equal(t, rune(0x82a0), id)
+	id, err = bmf.EncodeCharId(bmf.NewCharset(204), 'Ж')
+	require.NoError(t, err)
+	assert.Equal(t, rune(0xc6), id)
+
+	_, err = bmf.DecodeCharId(bmf.Charset{}, 0x81)
+	assert.Error(t, err, "undefined in Windows-1252")
+	_, err = bmf.EncodeCharId(bmf.Charset{}, 'Ж')
+	assert.Error(t, err)
+	_, err = bmf.CharsetEncoding(bmf.NewCharset(2))
+	assert.Error(t, err, "Symbol")
+	_, err = bmf.CharsetEncoding(bmf.ParseCharset("Klingon"))
+	assert.Error(t, err)
+
+	// a Windows-1251 font
+	fnt := &bmf.Font{
+		Info:     bmf.Info{Charset: bmf.ParseCharset("Russian")},
+		Chars:    []bmf.Char{{Id: -1}, {Id: 'A', XAdvance: 1}, {Id: 0xc0, XAdvance: 2}, {Id: 0xc2, XAdvance: 3}},
+		Kernings: []bmf.Kerning{{First: 0xc0, Second: 0xc2, Amount: -1}},
+	}
+	c, ok := bmf.FindChar(fnt, 'В')
+	assert.True(t, ok)
+	assert.Equal(t, 3, c.XAdvance)
+	_, ok = bmf.FindChar(fnt, 'Ж')
+	assert.False(t, ok)
+
+	uni, err := bmf.ToUnicode(fnt)
+	require.NoError(t, err)
+	assert.True(t, bool(uni.Info.Unicode))
+	assert.Equal(t, bmf.Charset{}, uni.Info.Charset)
+	assert.Equal(t, []bmf.Char{{Id: -1}, {Id: 'A', XAdvance: 1}, {Id: 'А', XAdvance: 2}, {Id: 'В', XAdvance: 3}}, uni.Chars)
+	assert.Equal(t, []bmf.Kerning{{First: 'А', Second: 'В', Amount: -1}}, uni.Kernings)
+	assert.Equal(t, rune(0xc0), fnt.Chars[2].Id, "input is not modified")
+	c, ok = bmf.FindChar(uni, 'В')
+	assert.True(t, ok)
+	assert.Equal(t, 3, c.XAdvance)
+
+	fnt.Info.Charset = bmf.Charset{Value: 2}
+	_, err = bmf.ToUnicode(fnt)
+	assert.Error(t, err)
+}
+
+func TestCharsetRoundTrip(t *testing.T) {
+	formats := map[string]struct {
+		serialize func(*bmf.Font, io.Writer) error
+		parse     func(io.Reader) (*bmf.Font, error)
+	}{
+		"text":   {bmf.SerializeText, bmf.ParseText},
+		"xml":    {bmf.SerializeXML, bmf.ParseXML},
+		"binary": {bmf.SerializeBinary, bmf.ParseBinary},
+	}
+	cases := []struct {
+		unicode bmf.BinBool
+		charset bmf.Charset
+	}{
+		{true, bmf.Charset{}},
+		{true, bmf.NewCharset(204)},
+		{false, bmf.NewCharset(0)},
+		{false, bmf.NewCharset(42)},
+		{false, bmf.ParseCharset("ShiftJIS")},
+	}
+	for name, format := range formats {
+		for _, c := range cases {
+			fnt := Expected
+			fnt.Info.Unicode = c.unicode
+			fnt.Info.Charset = c.charset
+			buf := &bytes.Buffer{}
+			require.NoError(t, format.serialize(&fnt, buf))
+			parsed, err := format.parse(buf)
+			require.NoError(t, err)
+			assert.Equalf(t, c.charset, parsed.Info.Charset, "%v %v", name, c.charset)
+		}
+	}
+
+	assert.Equal(t, "Russian", bmf.NewCharset(204).String())
+	assert.Equal(t, "42", bmf.Charset{Value: 42}.String())
+	assert.Equal(t, "", bmf.Charset{}.String())
+	assert.Equal(t, bmf.Charset{Value: 128, Name: "shiftjis"}, bmf.ParseCharset("shiftjis"))
+	assert.Equal(t, bmf.Charset{Name: "Klingon"}, bmf.ParseCharset("Klingon"))
+
+	// names that are not in the table are kept by the text formats
+	fnt := Expected
+	fnt.Info.Charset = bmf.ParseCharset("Klingon")
+	out, err := xml.Marshal(fnt.Info)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `charset="Klingon"`)
+}
+
+func TestChannels(t *testing.T) {
+	assert.Equal(t, "glyphAndOutline", bmf.GlyphAndOutline.String())
+	assert.Equal(t, "7", bmf.ChannelData(7).String())
+	assert.False(t, bmf.ChannelData(7).Valid())
+	assert.Equal(t, "red|alpha", (bmf.Red | bmf.Alpha).String())
+	assert.Equal(t, "all", bmf.All.String())
+	assert.Equal(t, "none", bmf.Channel(0).String())
+	assert.Equal(t, "blue|0x10", bmf.Channel(0x11).String())
+	assert.True(t, bmf.All.Has(bmf.Red|bmf.Green))
+	assert.False(t, bmf.Red.Has(bmf.Red|bmf.Green))
+	assert.Equal(t, "kerning pairs", bmf.BlockKerningPairs.String())
+
+	var data bmf.ChannelData
+	require.NoError(t, data.UnmarshalText([]byte("one")))
+	assert.Equal(t, bmf.One, data)
+	require.NoError(t, data.UnmarshalText([]byte("1")))
+	assert.Equal(t, bmf.Outline, data)
+	assert.Error(t, data.UnmarshalText([]byte("7")))
+	_, err := bmf.ChannelData(7).MarshalText()
+	assert.Error(t, err)
+
+	var ch bmf.Channel
+	require.NoError(t, ch.UnmarshalText([]byte("green|Blue")))
+	assert.Equal(t, bmf.Green|bmf.Blue, ch)
+	require.NoError(t, ch.UnmarshalText([]byte("15")))
+	assert.Equal(t, bmf.All, ch)
+	assert.Error(t, ch.UnmarshalText([]byte("16")))
+	assert.Error(t, ch.UnmarshalText([]byte("red|pink")))
+
+	var block bmf.BlockType
+	require.NoError(t, block.UnmarshalText([]byte("characters")))
+	assert.Equal(t, bmf.BlockChars, block)
+
+	// the file formats keep numbers, but reject invalid values
+	out, err := xml.Marshal(Expected.Chars[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `chnl="15"`)
+	for _, src := range []string{
+		"common lineHeight=1 alphaChnl=7\n",
+		"char id=65 chnl=16\n",
+	} {
+		_, err = bmf.ParseText(strings.NewReader(src))
+		assert.Errorf(t, err, "%q", src)
+	}
+	_, err = bmf.ParseXML(strings.NewReader(`<font><common alphaChnl="7"/></font>`))
+	assert.Error(t, err)
+	fnt, err := bmf.ParseXML(strings.NewReader(`<font><common alphaChnl="outline"/></font>`))
+	require.NoError(t, err)
+	assert.Equal(t, bmf.Outline, fnt.Common.AlphaChannel)
+
+	invalid := Expected
+	invalid.Common.AlphaChannel = 7
+	buf := &bytes.Buffer{}
+	require.NoError(t, bmf.SerializeBinary(&invalid, buf))
+	_, err = bmf.ParseBinary(buf)
+	assert.Error(t, err)
+}
+
+func TestSubset(t *testing.T) {
+	sub := bmf.Subset(&Expected, []rune{'A', 'V', 'x'})
+	assert.Equal(t, []bmf.Char{Expected.Chars[0], Expected.Chars[1], Expected.Chars[3]}, sub.Chars)
+	assert.Equal(t, []bmf.Kerning{Expected.Kernings[0], Expected.Kernings[2]}, sub.Kernings)
+	assert.Equal(t, Expected.Pages, sub.Pages)
+
+	pages := testPages(&Expected)
+	sub, subPages, err := bmf.SubsetPages(&Expected, pages, []rune{'A'})
+	require.NoError(t, err)
+	assert.Equal(t, 1, sub.Common.Pages)
+	assert.Equal(t, []bmf.Page{{Id: 0, File: "test-bin_0.png"}}, sub.Pages)
+	assert.Equal(t, 32, sub.Common.ScaleW)
+	assert.Equal(t, 64, sub.Common.ScaleH)
+	assertGlyphsEqual(t, &Expected, pages, sub, subPages)
+}
+
+func TestRepack(t *testing.T) {
+	pages := testPages(&Expected)
+	fnt, newPages, err := bmf.Repack(&Expected, pages, bmf.RepackOptions{Width: 64, Height: 64})
+	require.NoError(t, err)
+	assert.Equal(t, 1, fnt.Common.Pages)
+	assert.Equal(t, 64, fnt.Common.ScaleW)
+	assert.Equal(t, []bmf.Page{{Id: 0, File: "test-bin_0.png"}}, fnt.Pages)
+	assert.Equal(t, Expected.Kernings, fnt.Kernings)
+	assertGlyphsEqual(t, &Expected, pages, fnt, newPages)
+
+	// glyphs keep the spacing between each other
+	for i, a := range fnt.Chars {
+		for _, b := range fnt.Chars[i+1:] {
+			ra := image.Rect(a.X, a.Y, a.X+a.Width+Expected.Info.Spacing.Horizontal, a.Y+a.Height+Expected.Info.Spacing.Vertical)
+			rb := image.Rect(b.X, b.Y, b.X+b.Width, b.Y+b.Height)
+			assert.Falsef(t, ra.Overlaps(rb), "chars %d and %d overlap", a.Id, b.Id)
+		}
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, bmf.SavePages(dir, fnt, newPages))
+	loaded, err := bmf.LoadPages(os.DirFS(dir), fnt)
+	require.NoError(t, err)
+	assertGlyphsEqual(t, &Expected, pages, fnt, loaded)
+
+	_, _, err = bmf.Repack(&Expected, pages, bmf.RepackOptions{Width: 16, Height: 16})
+	assert.Error(t, err)
+}
+
+func TestMerge(t *testing.T) {
+	latin := bmf.Subset(&Expected, []rune{'A', 'T'})
+	other := bmf.Subset(&Expected, []rune{'A', 'V'})
+	for i := range other.Pages {
+		other.Pages[i].File = fmt.Sprintf("other_%d.png", i)
+	}
+	other.Common.Base += 2
+	other.Common.LineHeight += 1
+	other.Chars[1].XAdvance = 99
+
+	merged, err := bmf.Merge(latin, other)
+	require.NoError(t, err)
+	assert.Equal(t, 4, merged.Common.Pages)
+	assert.Equal(t, Expected.Common.Base+2, merged.Common.Base)
+	assert.Equal(t, Expected.Common.LineHeight+2, merged.Common.LineHeight)
+	require.Len(t, merged.Pages, 4)
+	assert.Equal(t, 3, merged.Pages[3].Id)
+
+	ids := []rune{}
+	for _, c := range merged.Chars {
+		ids = append(ids, c.Id)
+	}
+	assert.Equal(t, []rune{-1, 65, 84, 86}, ids)
+	assert.Equal(t, Expected.Chars[1].XAdvance, merged.Chars[1].XAdvance, "first char wins")
+	assert.Equal(t, Expected.Chars[1].YOffset+2, merged.Chars[1].YOffset, "moved to the lower baseline")
+	assert.Equal(t, Expected.Chars[3].YOffset, merged.Chars[3].YOffset)
+	assert.Equal(t, Expected.Chars[3].Page+2, merged.Chars[3].Page)
+	// the pairs of the other font were kerned for its own 'A'
+	assert.Equal(t, latin.Kernings, merged.Kernings)
+
+	merged, err = bmf.MergeOptions{Conflict: bmf.KeepLast}.Merge(latin, other)
+	require.NoError(t, err)
+	assert.Equal(t, 99, merged.Chars[1].XAdvance)
+	assert.Equal(t, other.Kernings, merged.Kernings)
+
+	_, err = bmf.MergeOptions{Conflict: bmf.FailOnConflict}.Merge(latin, other)
+	assert.Error(t, err)
+
+	// duplicates within one font are no conflict, the last one is kept
+	dup := bmf.Subset(&Expected, []rune{'V'})
+	dup.Pages = []bmf.Page{{Id: 0, File: "dup_0.png"}, {Id: 1, File: "dup_1.png"}}
+	v := dup.Chars[len(dup.Chars)-1]
+	v.XAdvance = 42
+	dup.Chars = append(dup.Chars, v)
+	dup.Kernings = []bmf.Kerning{{First: 'V', Second: 'V', Amount: 1}, {First: 'V', Second: 'V', Amount: 2}}
+	for _, conflict := range []bmf.MergeConflict{bmf.KeepFirst, bmf.KeepLast, bmf.FailOnConflict} {
+		merged, err = bmf.MergeOptions{Conflict: conflict}.Merge(latin, dup)
+		require.NoError(t, err, conflict)
+		byId := map[rune]bmf.Char{}
+		for _, c := range merged.Chars {
+			byId[c.Id] = c
+		}
+		assert.Len(t, merged.Chars, len(byId), "no duplicate chars")
+		assert.Equal(t, 42, byId['V'].XAdvance)
+		assert.Equal(t, 2, bmf.NewKerningTable(merged.Kernings).Lookup('V', 'V'))
+		assert.Len(t, merged.Kernings, len(latin.Kernings)+1)
+	}
+
+	// separate exports usually have the same page file names
+	_, err = bmf.Merge(latin, bmf.Subset(&Expected, []rune{'V'}))
+	assert.Equal(t, bmf.IncompatibleError{Font: 1, Field: "Page.File", Reason: `"test-bin_0.png" is also used by font 0`}, err)
+
+	other.Common.Packed = true
+	_, err = bmf.Merge(latin, other)
+	assert.Equal(t, bmf.IncompatibleError{Font: 1, Field: "Common.Packed"}, err)
+}
+
+func TestScale(t *testing.T) {
+	fnt := bmf.Scale(&Expected, 0.5)
+	assert.Equal(t, -13, fnt.Info.Size)
+	assert.Equal(t, 14, fnt.Common.LineHeight)
+	assert.Equal(t, 11, fnt.Common.Base)
+	assert.Equal(t, Expected.Chars[0].Width, fnt.Chars[0].Width)
+	assert.Equal(t, -2, fnt.Chars[0].XOffset)
+	assert.Equal(t, 10, fnt.Chars[0].XAdvance)
+	assert.Equal(t, -1, fnt.Kernings[0].Amount)
+	assert.Equal(t, -3, Expected.Chars[0].XOffset, "the original is not modified")
+
+	fnt = bmf.ScaleOptions{Rounding: bmf.RoundDown}.Scale(&Expected, 0.5)
+	assert.Equal(t, 13, fnt.Common.LineHeight)
+	assert.Equal(t, -2, fnt.Chars[0].XOffset)
+	fnt = bmf.ScaleOptions{Rounding: bmf.RoundUp}.Scale(&Expected, 0.5)
+	assert.Equal(t, 14, fnt.Common.LineHeight)
+	assert.Equal(t, -1, fnt.Chars[0].XOffset)
+
+	frac := bmf.ScaleFrac(&Expected, 0.5)
+	assert.Equal(t, -1.5, frac[0].XOffset)
+	assert.Equal(t, 9.5, frac[0].XAdvance)
+	assert.Equal(t, 11.5, frac[0].DrawHeight)
+	assert.Equal(t, Expected.Chars[0].Height, frac[0].Height)
+}
+
+func TestDiff(t *testing.T) {
+	a := Expected
+	b := *bmf.Subset(&Expected, []rune{'A', 'T'})
+	b.Info.Face = "Arial Bold"
+	b.Info.Padding.Up = 0
+	b.Pages[1].File = "other.png"
+	b.Chars[1].XAdvance = 20
+	b.Chars = append(b.Chars, bmf.Char{Id: 'x'})
+	b.Kernings[0].Amount = -1
+	b.Kernings = append(b.Kernings, bmf.Kerning{First: 'x', Second: 'A', Amount: 1})
+
+	assert.True(t, bmf.Diff(&a, &a).Empty())
+
+	d := bmf.Diff(&a, &b)
+	assert.Equal(t, []bmf.FieldChange{
+		{Field: "face", Old: `"Arial"`, New: `"Arial Bold"`},
+		{Field: "padding", Old: "1,2,3,4", New: "0,2,3,4"},
+	}, d.Info)
+	assert.Empty(t, d.Common)
+	assert.Equal(t, "info: face \"Arial\" → \"Arial Bold\", padding 1,2,3,4 → 0,2,3,4\n"+
+		"page 1: \"test-bin_1.png\" → \"other.png\"\n"+
+		"char U+0041: xadvance 19 → 20\n"+
+		"- char U+0056\n"+
+		"+ char U+0078\n"+
+		"- kerning U+0041 U+0056: -2\n"+
+		"kerning U+0054 U+0041: -2 → -1\n"+
+		"- kerning U+0056 U+0041: -2\n"+
+		"+ kerning U+0078 U+0041: 1\n", d.String())
+}
+
+func TestKerningTable(t *testing.T) {
+	table := bmf.NewKerningTable(Expected.Kernings)
+	assert.Equal(t, 4, table.Len())
+	for _, k := range Expected.Kernings {
+		assert.Equal(t, k.Amount, table.Lookup(k.First, k.Second))
+	}
+	assert.Equal(t, 0, table.Lookup('A', 'A'))
+	assert.Equal(t, 0, table.Lookup('Z', 'A'))
+	assert.Equal(t, bmf.Normalize(&Expected).Kernings, table.Kernings())
+
+	empty := bmf.NewKerningTable(nil)
+	assert.Equal(t, 0, empty.Lookup('A', 'V'))
+	assert.Empty(t, empty.Kernings())
+}
+
+func TestSynthesizeKerning(t *testing.T) {
+	// 'n' is a box, 'L' and 'T' leave a gap that optical kerning closes
+	page := image.NewNRGBA(image.Rect(0, 0, 32, 8))
+	ink := image.NewUniform(color.NRGBA{A: 255})
+	draw.Draw(page, image.Rect(0, 0, 4, 8), ink, image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(8, 0, 9, 8), ink, image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(8, 7, 13, 8), ink, image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(16, 0, 21, 1), ink, image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(18, 0, 19, 8), ink, image.Point{}, draw.Src)
+
+	fnt := &bmf.Font{
+		Common: bmf.Common{ScaleW: 32, ScaleH: 8, Pages: 1},
+		Pages:  []bmf.Page{{Id: 0, File: "page_0.png"}},
+		Chars: []bmf.Char{
+			{Id: 'n', X: 0, Width: 4, Height: 8, XOffset: 1, XAdvance: 6},
+			{Id: 'L', X: 8, Width: 5, Height: 8, XOffset: 1, XAdvance: 6},
+			{Id: 'T', X: 16, Width: 5, Height: 8, XOffset: 1, XAdvance: 6},
+		},
+	}
+	pages := []*image.NRGBA{page}
+
+	kerned, err := bmf.KerningOptions{Threshold: 2}.SynthesizeKerning(fnt, pages)
+	require.NoError(t, err)
+	assert.Equal(t, []bmf.Kerning{{First: 'L', Second: 'T', Amount: -2}}, kerned.Kernings)
+	assert.Empty(t, fnt.Kernings, "input is not modified")
+
+	kerned, err = bmf.KerningOptions{MaxPairs: 1}.SynthesizeKerning(fnt, pages)
+	require.NoError(t, err)
+	assert.Equal(t, []bmf.Kerning{{First: 'L', Second: 'T', Amount: -2}}, kerned.Kernings)
+
+	fnt.Kernings = []bmf.Kerning{{First: 'L', Second: 'T', Amount: -3}}
+	kerned, err = bmf.SynthesizeKerning(fnt, pages)
+	require.NoError(t, err)
+	assert.Len(t, kerned.Kernings, 4)
+	assert.Equal(t, -3, bmf.NewKerningTable(kerned.Kernings).Lookup('L', 'T'), "existing pairs are kept")
+
+	_, err = bmf.SynthesizeKerning(fnt, nil)
+	assert.Error(t, err)
+
+	// the chars and pages are not sorted or renumbered, so they still match the page images
+	unsorted := &bmf.Font{
+		Common: bmf.Common{ScaleW: 32, ScaleH: 8, Pages: 1},
+		Pages:  []bmf.Page{{Id: 2, File: "page_2.png"}},
+		Chars:  []bmf.Char{fnt.Chars[2], fnt.Chars[0], fnt.Chars[1]},
+	}
+	for i := range unsorted.Chars {
+		unsorted.Chars[i].Page = 2
+	}
+	kerned, err = bmf.KerningOptions{Threshold: 2}.SynthesizeKerning(unsorted, []*image.NRGBA{nil, nil, page})
+	require.NoError(t, err)
+	assert.Equal(t, unsorted.Pages, kerned.Pages)
+	assert.Equal(t, unsorted.Chars, kerned.Chars)
+	assert.Equal(t, []bmf.Kerning{{First: 'L', Second: 'T', Amount: -2}}, kerned.Kernings)
+}
+
+func TestGenerate(t *testing.T) {
+	opts := bmf.GenerateOptions{
+		Runes:    []rune("AVT a"),
+		Size:     32,
+		Padding:  bmf.Padding{Up: 1, Right: 2, Down: 3, Left: 4},
+		Spacing:  bmf.Spacing{Horizontal: 2, Vertical: 1},
+		Outline:  2,
+		Width:    64,
+		Height:   64,
+		PageFile: "go_%d.png",
+	}
+	fnt, pages, err := bmf.Generate(goregular.TTF, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "Go", fnt.Info.Face)
+	assert.Equal(t, -32, fnt.Info.Size)
+	assert.Equal(t, opts.Padding, fnt.Info.Padding)
+	assert.Equal(t, opts.Spacing, fnt.Info.Spacing)
+	assert.Equal(t, 2, fnt.Info.Outline)
+	assert.Equal(t, bmf.Outline, fnt.Common.AlphaChannel)
+	assert.Equal(t, bmf.Glyph, fnt.Common.RedChannel)
+	assert.Equal(t, 64, fnt.Common.ScaleW)
+	assert.Equal(t, len(pages), fnt.Common.Pages)
+	assert.Equal(t, "go_0.png", fnt.Pages[0].File)
+	require.Len(t, fnt.Chars, 5)
+
+	space := fnt.Chars[3]
+	assert.Equal(t, ' ', space.Id)
+	assert.Zero(t, space.Width)
+	assert.Greater(t, space.XAdvance, 0)
+
+	a := fnt.Chars[0]
+	assert.Equal(t, 'A', a.Id)
+	assert.Greater(t, a.XAdvance, 0)
+	assert.Less(t, a.YOffset, fnt.Common.Base)
+	assert.Equal(t, -4-2, a.XOffset, "padding and outline move the glyph left")
+	page := pages[a.Page]
+	// the padding stays empty, the outline surrounds the glyph
+	assert.Zero(t, page.NRGBAAt(a.X, a.Y).A)
+	var glyph, outline bool
+	for y := a.Y; y < a.Y+a.Height; y++ {
+		for x := a.X; x < a.X+a.Width; x++ {
+			c := page.NRGBAAt(x, y)
+			assert.GreaterOrEqual(t, c.A, c.R)
+			glyph = glyph || c.R == 255
+			outline = outline || c.A == 255 && c.R == 0
+		}
+	}
+	assert.True(t, glyph)
+	assert.True(t, outline)
+
+	// the result can be written like any other font
+	buf := &bytes.Buffer{}
+	require.NoError(t, bmf.SerializeText(fnt, buf))
+	parsed, err := bmf.ParseText(buf)
+	require.NoError(t, err)
+	assertFontEqual(t, *fnt, *parsed)
+
+	_, _, err = bmf.Generate(goregular.TTF, bmf.GenerateOptions{})
+	assert.Error(t, err)
+	_, _, err = bmf.Generate([]byte("not a font"), opts)
+	assert.Error(t, err)
+	opts.Width = 16
+	_, _, err = bmf.Generate(goregular.TTF, opts)
+	assert.Error(t, err)
+}
+
+// kernedFace adds a kerning pair to a face
+type kernedFace struct {
+	font.Face
+	calls *int
+}
+
+func (f kernedFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	if f.calls != nil {
+		*f.calls++
+	}
+	if r0 == 'A' && r1 == 'V' {
+		return -fixed.I(1)
+	}
+	return 0
+}
+
+func TestGenerateFace(t *testing.T) {
+	face := kernedFace{Face: basicfont.Face7x13}
+	fnt, pages, err := bmf.GenerateFace(face, bmf.GenerateOptions{Runes: []rune("AV \u00e9\u4e00"), Width: 32, Height: 32})
+	require.NoError(t, err)
+	assert.Equal(t, -13, fnt.Info.Size)
+	assert.Equal(t, 13, fnt.Common.LineHeight)
+	assert.Equal(t, 11, fnt.Common.Base)
+	assert.Equal(t, bmf.Glyph, fnt.Common.AlphaChannel)
+	assert.Equal(t, bmf.One, fnt.Common.RedChannel)
+	assert.Equal(t, []bmf.Kerning{{First: 'A', Second: 'V', Amount: -1}}, fnt.Kernings)
+	require.Len(t, pages, 1)
+
+	ids := []rune{}
+	for _, c := range fnt.Chars {
+		ids = append(ids, c.Id)
+		assert.Equal(t, 7, c.XAdvance)
+	}
+	assert.Equal(t, []rune{'A', 'V', ' '}, ids, "missing glyphs are skipped")
+
+	// glyphs are trimmed to their pixels
+	a := fnt.Chars[0]
+	assert.Less(t, a.Width, 7)
+	assert.Less(t, a.Height, 13)
+	assert.Zero(t, fnt.Chars[2].Width)
+	for y := 0; y < a.Height; y++ {
+		for x := 0; x < a.Width; x++ {
+			dr, mask, maskp, _, _ := face.Glyph(fixed.Point26_6{}, 'A')
+			_, _, _, alpha := mask.At(maskp.X+a.XOffset-dr.Min.X+x, maskp.Y+a.YOffset-fnt.Common.Base-dr.Min.Y+y).RGBA()
+			assert.Equal(t, uint8(alpha>>8), pages[0].NRGBAAt(a.X+x, a.Y+y).A)
+		}
+	}
+
+	fnt, _, err = bmf.GenerateFace(face, bmf.GenerateOptions{Runes: []rune("AV"), SkipKernings: true})
+	require.NoError(t, err)
+	assert.Empty(t, fnt.Kernings)
+
+	// only the pairs of KerningRunes are queried
+	calls := 0
+	face.calls = &calls
+	fnt, _, err = bmf.GenerateFace(face, bmf.GenerateOptions{KerningRunes: []rune("AVW\u4e00")})
+	require.NoError(t, err)
+	assert.Equal(t, 9, calls)
+	assert.Equal(t, []bmf.Kerning{{First: 'A', Second: 'V', Amount: -1}}, fnt.Kernings)
+	fnt, _, err = bmf.GenerateFace(face, bmf.GenerateOptions{KerningRunes: []rune("V")})
+	require.NoError(t, err)
+	assert.Empty(t, fnt.Kernings)
+}
+
+func TestBakeEffect(t *testing.T) {
+	// a white 4x4 square in the alpha channel
+	page := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(page, image.Rect(0, 0, 4, 4), image.NewUniform(color.NRGBA{R: 255, G: 255, B: 255, A: 255}), image.Point{}, draw.Src)
+	fnt := &bmf.Font{
+		Common: bmf.Common{ScaleW: 16, ScaleH: 16, Pages: 1, AlphaChannel: bmf.Glyph, RedChannel: bmf.One, GreenChannel: bmf.One, BlueChannel: bmf.One},
+		Pages:  []bmf.Page{{Id: 0, File: "font_0.png"}},
+		Chars:  []bmf.Char{{Id: 'A', Width: 4, Height: 4, XOffset: 1, YOffset: 2, XAdvance: 5, Channel: bmf.All}, {Id: ' ', XAdvance: 3}},
+	}
+	pages := []*image.NRGBA{page}
+
+	outlined, newPages, err := bmf.BakeEffect(fnt, pages, bmf.EffectOptions{Thickness: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, outlined.Info.Outline)
+	assert.Equal(t, bmf.Padding{Up: 1, Right: 1, Down: 1, Left: 1}, outlined.Info.Padding)
+	assert.Equal(t, bmf.Outline, outlined.Common.AlphaChannel)
+	assert.Equal(t, bmf.Glyph, outlined.Common.RedChannel, "the glyph moves to the color channels")
+	assert.Equal(t, []bmf.Page{{Id: 0, File: "font_0.png"}}, outlined.Pages)
+	a := outlined.Chars[0]
+	assert.Equal(t, 6, a.Width)
+	assert.Equal(t, 6, a.Height)
+	assert.Equal(t, 0, a.XOffset)
+	assert.Equal(t, 1, a.YOffset)
+	assert.Equal(t, 5, a.XAdvance)
+	assert.Zero(t, outlined.Chars[1].Width)
+	at := func(x, y int) color.NRGBA {
+		return newPages[0].NRGBAAt(a.X+x, a.Y+y)
+	}
+	assert.Equal(t, color.NRGBA{}, at(0, 0), "the outline is round")
+	assert.Equal(t, color.NRGBA{A: 255}, at(0, 1))
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, at(1, 1))
+
+	shadowed, newPages, err := bmf.BakeEffect(fnt, pages, bmf.EffectOptions{Offset: image.Pt(2, 1), Channel: bmf.Red, Combine: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, shadowed.Info.Outline)
+	assert.Equal(t, bmf.Padding{Right: 2, Down: 1}, shadowed.Info.Padding)
+	assert.Equal(t, bmf.GlyphAndOutline, shadowed.Common.RedChannel)
+	assert.Equal(t, bmf.Glyph, shadowed.Common.AlphaChannel)
+	assert.Equal(t, bmf.One, shadowed.Common.GreenChannel)
+	a = shadowed.Chars[0]
+	assert.Equal(t, 6, a.Width)
+	assert.Equal(t, 5, a.Height)
+	assert.Equal(t, 1, a.XOffset)
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, at(0, 0))
+	assert.Equal(t, color.NRGBA{R: 127, G: 255, B: 255}, at(5, 4), "shadow only")
+	assert.Equal(t, color.NRGBA{R: 0, G: 255, B: 255}, at(0, 4))
+
+	fnt.Common.Packed = true
+	_, _, err = bmf.BakeEffect(fnt, pages, bmf.EffectOptions{Thickness: 1})
+	assert.Equal(t, bmf.ErrPacked, err)
+}
+
+func TestConvertSDF(t *testing.T) {
+	// a white 8x8 square in the alpha channel
+	page := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(page, image.Rect(0, 0, 8, 8), image.NewUniform(color.NRGBA{R: 255, G: 255, B: 255, A: 255}), image.Point{}, draw.Src)
+	fnt := &bmf.Font{
+		Info:   bmf.Info{Outline: 1},
+		Common: bmf.Common{ScaleW: 32, ScaleH: 32, Pages: 1, AlphaChannel: bmf.Glyph, RedChannel: bmf.One, GreenChannel: bmf.One, BlueChannel: bmf.One},
+		Pages:  []bmf.Page{{Id: 0, File: "font_0.png"}},
+		Chars:  []bmf.Char{{Id: 'A', Width: 8, Height: 8, XOffset: 1, YOffset: 2, XAdvance: 9, Channel: bmf.All}, {Id: ' ', XAdvance: 3}},
+	}
+
+	sdf, pages, err := bmf.ConvertSDF(fnt, []*image.NRGBA{page}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, &bmf.DistanceField{FieldType: "sdf", DistanceRange: 4}, sdf.DistanceField)
+	assert.Equal(t, bmf.Padding{Up: 2, Right: 2, Down: 2, Left: 2}, sdf.Info.Padding)
+	assert.Equal(t, 0, sdf.Info.Outline)
+	a := sdf.Chars[0]
+	assert.Equal(t, 12, a.Width)
+	assert.Equal(t, 12, a.Height)
+	assert.Equal(t, -1, a.XOffset)
+	assert.Equal(t, 0, a.YOffset)
+	assert.Zero(t, sdf.Chars[1].Width)
+
+	alpha := func(x, y int) uint8 {
+		return pages[0].NRGBAAt(a.X+x, a.Y+y).A
+	}
+	assert.Equal(t, uint8(0), alpha(0, 0), "beyond the spread")
+	assert.Equal(t, uint8(255), alpha(6, 6), "beyond the spread")
+	assert.Greater(t, alpha(2, 6), uint8(128), "inside of the edge")
+	assert.Less(t, alpha(1, 6), uint8(128), "outside of the edge")
+	assert.Less(t, alpha(0, 6), alpha(1, 6))
+	assert.Equal(t, uint8(255), pages[0].NRGBAAt(a.X, a.Y).R)
+
+	// the distance field is kept by the text and XML formats
+	buf := &bytes.Buffer{}
+	require.NoError(t, bmf.SerializeText(sdf, buf))
+	assert.Contains(t, buf.String(), "distanceField fieldType=\"sdf\" distanceRange=4\n")
+	parsed, err := bmf.ParseText(buf)
+	require.NoError(t, err)
+	assert.Equal(t, sdf.DistanceField, parsed.DistanceField)
+
+	buf.Reset()
+	require.NoError(t, bmf.SerializeXML(sdf, buf))
+	parsed, err = bmf.ParseXML(buf)
+	require.NoError(t, err)
+	assert.Equal(t, sdf.DistanceField, parsed.DistanceField)
+	assert.Contains(t, bmf.Diff(sdf, fnt).String(), "distanceField: fieldType \"sdf\" → \"\", distanceRange 4 → 0\n")
+
+	// field types with spaces are quoted, ones that need escaping are rejected
+	spaced := *sdf
+	spaced.DistanceField = &bmf.DistanceField{FieldType: "multi channel", DistanceRange: 4}
+	buf.Reset()
+	require.NoError(t, bmf.SerializeText(&spaced, buf))
+	parsed, err = bmf.ParseText(buf)
+	require.NoError(t, err)
+	assert.Equal(t, spaced.DistanceField, parsed.DistanceField)
+	spaced.DistanceField = &bmf.DistanceField{FieldType: `a"b`}
+	assert.Error(t, bmf.SerializeText(&spaced, io.Discard))
+
+	_, _, err = bmf.ConvertSDF(fnt, []*image.NRGBA{page}, 0)
+	assert.Error(t, err)
+}
+
+func TestMarshalers(t *testing.T) {
+	data, err := Expected.MarshalBinary()
+	require.NoError(t, err)
+	var fnt bmf.Font
+	require.NoError(t, fnt.UnmarshalBinary(data))
+	assertFontEqual(t, Expected, fnt)
+
+	data, err = Expected.MarshalText()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "info face="))
+	fnt = bmf.Font{}
+	require.NoError(t, fnt.UnmarshalText(data))
+	assertFontEqual(t, Expected, fnt)
+
+	// gob uses the text format, which keeps the distance field of SDF fonts
+	buf := &bytes.Buffer{}
+	require.NoError(t, gob.NewEncoder(buf).Encode(Expected))
+	fnt = bmf.Font{}
+	require.NoError(t, gob.NewDecoder(buf).Decode(&fnt))
+	assertFontEqual(t, Expected, fnt)
+
+	sdfFont := Expected
+	sdfFont.DistanceField = &bmf.DistanceField{FieldType: "sdf", DistanceRange: 4}
+	buf.Reset()
+	require.NoError(t, gob.NewEncoder(buf).Encode(&sdfFont))
+	fnt = bmf.Font{}
+	require.NoError(t, gob.NewDecoder(buf).Decode(&fnt))
+	assertFontEqual(t, sdfFont, fnt)
+	assert.Equal(t, sdfFont.DistanceField, fnt.DistanceField)
+
+	// json encodes the font as object, also when it is embedded
+	type asset struct {
+		Name string
+		Font *bmf.Font
+	}
+	sdf := Expected
+	sdf.DistanceField = &bmf.DistanceField{FieldType: "sdf", DistanceRange: 4}
+	data, err = json.Marshal(asset{Name: "test", Font: &sdf})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Font":{"info":{"face":"Arial"`)
+	assert.Contains(t, string(data), `"alphaChnl":"glyph"`)
+	var decoded asset
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NotNil(t, decoded.Font)
+	assertFontEqual(t, sdf, *decoded.Font)
+	assert.Equal(t, sdf.DistanceField, decoded.Font.DistanceField)
+
+	// XML still uses the XML format
+	data, err = xml.Marshal(Expected)
+	require.NoError(t, err)
+	fnt = bmf.Font{}
+	require.NoError(t, xml.Unmarshal(data, &fnt))
+	assertFontEqual(t, Expected, fnt)
+
+	assert.Error(t, fnt.UnmarshalBinary([]byte("BMF")))
+	assert.Error(t, fnt.UnmarshalText([]byte("info face=\"")))
+}
+
+func BenchmarkKerningTableLookup(b *testing.B) {
+	fnt := largeFont(100000)
+	table := bmf.NewKerningTable(fnt.Kernings)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := fnt.Kernings[i%len(fnt.Kernings)]
+		if table.Lookup(k.First, k.Second) != k.Amount {
+			b.Fatal("wrong amount")
+		}
+	}
+}
+
+// testPages creates page images for fnt where every glyph is filled with a color derived from its id
+func testPages(fnt *bmf.Font) []*image.NRGBA {
+	pages := make([]*image.NRGBA, len(fnt.Pages))
+	for i := range pages {
+		pages[i] = image.NewNRGBA(image.Rect(0, 0, fnt.Common.ScaleW, fnt.Common.ScaleH))
+	}
+	for _, c := range fnt.Chars {
+		rect := image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height)
+		clr := color.NRGBA{R: uint8(c.Id), G: uint8(c.Id >> 8), B: 128, A: 255}
+		draw.Draw(pages[c.Page], rect, image.NewUniform(clr), image.Point{}, draw.Src)
+		// mark the corner to catch flipped copies
+		pages[c.Page].Set(c.X, c.Y, color.NRGBA{A: 255})
+	}
+	return pages
+}
+
+// assertGlyphsEqual checks that the glyphs of all chars in actual look like the ones in expected
+func assertGlyphsEqual(t *testing.T, expected *bmf.Font, expectedPages []*image.NRGBA, actual *bmf.Font, actualPages []*image.NRGBA) {
+	byId := map[rune]bmf.Char{}
+	for _, c := range expected.Chars {
+		byId[c.Id] = c
+	}
+
+	for _, a := range actual.Chars {
+		e, ok := byId[a.Id]
+		if !assert.Truef(t, ok, "unexpected char %d", a.Id) {
+			continue
+		}
+		require.Equal(t, e.Width, a.Width)
+		require.Equal(t, e.Height, a.Height)
+		for y := 0; y < a.Height; y++ {
+			for x := 0; x < a.Width; x++ {
+				ec := expectedPages[e.Page].At(e.X+x, e.Y+y)
+				ac := actualPages[a.Page].At(a.X+x, a.Y+y)
+				require.Equalf(t, ec, ac, "char %d at %d,%d", a.Id, x, y)
+			}
+		}
+	}
+}
+
 func assertFontEqual(t *testing.T, expected bmf.Font, actual bmf.Font) {
 	assert.Equal(t, expected.Info, actual.Info)
 	assert.Equal(t, expected.Common, actual.Common)
@@ -179,3 +1420,81 @@ func assertFontEqual(t *testing.T, expected bmf.Font, actual bmf.Font) {
 	assert.Equal(t, expected.Chars, actual.Chars)
 	assert.Equal(t, expected.Kernings, actual.Kernings)
 }
+
+func TestParseTextTokens(t *testing.T) {
+	src := "info face=\"Comic Sans\" size=\"12\" charset=ANSI padding=1,2\n" +
+		"page id=0 file=\"a b.png\"\n"
+	fnt, err := bmf.ParseText(bytes.NewBufferString(src))
+	require.NoError(t, err)
+	assert.Equal(t, "Comic Sans", fnt.Info.Face)
+	assert.Equal(t, 12, fnt.Info.Size)
+	assert.Equal(t, bmf.Charset{Value: 0, Name: "ANSI"}, fnt.Info.Charset)
+	assert.Equal(t, bmf.Padding{Up: 1, Right: 2}, fnt.Info.Padding)
+	assert.Equal(t, []bmf.Page{{Id: 0, File: "a b.png"}}, fnt.Pages)
+
+	for _, line := range []string{"info face=\"Arial", "char id", "char id=1=2", "\n"} {
+		_, err = bmf.ParseText(bytes.NewBufferString(line))
+		assert.IsTypef(t, bmf.TextParseError{}, err, "line %q", line)
+	}
+}
+
+// largeFont returns a copy of Expected with n characters and n kerning pairs
+func largeFont(n int) *bmf.Font {
+	fnt := Expected
+	fnt.Chars = make([]bmf.Char, n)
+	fnt.Kernings = make([]bmf.Kerning, n)
+	for i := range fnt.Chars {
+		c := Expected.Chars[i%len(Expected.Chars)]
+		c.Id = rune(i + 32)
+		fnt.Chars[i] = c
+		fnt.Kernings[i] = bmf.Kerning{First: rune(i + 32), Second: rune(i + 33), Amount: -(i % 3)}
+	}
+	return &fnt
+}
+
+func benchmarkParse(b *testing.B, serialize func(*bmf.Font, io.Writer) error, parse func(io.Reader) (*bmf.Font, error)) {
+	data := &bytes.Buffer{}
+	require.NoError(b, serialize(largeFont(20000), data))
+
+	b.SetBytes(int64(data.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parse(bytes.NewReader(data.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseText(b *testing.B) {
+	benchmarkParse(b, bmf.SerializeText, bmf.ParseText)
+}
+
+func BenchmarkParseBinary(b *testing.B) {
+	benchmarkParse(b, bmf.SerializeBinary, bmf.ParseBinary)
+}
+
+func BenchmarkParseBinaryBytes(b *testing.B) {
+	data := &bytes.Buffer{}
+	require.NoError(b, bmf.SerializeBinary(largeFont(20000), data))
+
+	b.SetBytes(int64(data.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := bmf.ParseBinaryBytes(data.Bytes()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSerializeBinary(b *testing.B) {
+	fnt := largeFont(20000)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := bmf.SerializeBinary(fnt, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package bmf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var channelDataNames = [...]string{
+	Glyph:           "glyph",
+	Outline:         "outline",
+	GlyphAndOutline: "glyphAndOutline",
+	Zero:            "zero",
+	One:             "one",
+}
+
+// Valid reports whether d is one of the defined channel data types
+func (d ChannelData) Valid() bool {
+	return d >= Glyph && d <= One
+}
+
+// String returns the name of the channel data type, e.g. "glyph", or its number if it is not valid
+func (d ChannelData) String() string {
+	if !d.Valid() {
+		return strconv.Itoa(int(d))
+	}
+	return channelDataNames[d]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d ChannelData) MarshalText() ([]byte, error) {
+	if !d.Valid() {
+		return nil, fmt.Errorf("invalid channel data %d", int(d))
+	}
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It accepts the names returned by String and numbers.
+func (d *ChannelData) UnmarshalText(text []byte) error {
+	s := string(text)
+	for i, name := range channelDataNames {
+		if strings.EqualFold(s, name) {
+			*d = ChannelData(i)
+			return nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || !ChannelData(v).Valid() {
+		return fmt.Errorf("invalid channel data %q", s)
+	}
+	*d = ChannelData(v)
+	return nil
+}
+
+// channelNames lists the channel bits with their names in the order of String
+var channelNames = []struct {
+	channel Channel
+	name    string
+}{
+	{Red, "red"},
+	{Green, "green"},
+	{Blue, "blue"},
+	{Alpha, "alpha"},
+}
+
+// Valid reports whether c only has the bits of the four color channels set
+func (c Channel) Valid() bool {
+	return c&^All == 0
+}
+
+// Has reports whether all channels of other are set in c
+func (c Channel) Has(other Channel) bool {
+	return c&other == other
+}
+
+// String returns the names of the channels separated by "|", e.g. "red|alpha", "all" or "none".
+// Unknown bits are added as a hexadecimal number.
+func (c Channel) String() string {
+	switch c {
+	case 0:
+		return "none"
+	case All:
+		return "all"
+	}
+
+	var names []string
+	for _, ch := range channelNames {
+		if c.Has(ch.channel) {
+			names = append(names, ch.name)
+		}
+	}
+	if rest := c &^ All; rest != 0 {
+		names = append(names, fmt.Sprintf("%#x", int(rest)))
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (c Channel) MarshalText() ([]byte, error) {
+	if !c.Valid() {
+		return nil, fmt.Errorf("invalid channel %d", int(c))
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It accepts the names returned by String and numbers.
+func (c *Channel) UnmarshalText(text []byte) error {
+	s := string(text)
+	if v, err := strconv.Atoi(s); err == nil {
+		if !Channel(v).Valid() {
+			return fmt.Errorf("invalid channel %q", s)
+		}
+		*c = Channel(v)
+		return nil
+	}
+
+	switch strings.ToLower(s) {
+	case "none":
+		*c = 0
+		return nil
+	case "all":
+		*c = All
+		return nil
+	}
+
+	var v Channel
+outer:
+	for _, part := range strings.Split(s, "|") {
+		for _, ch := range channelNames {
+			if strings.EqualFold(part, ch.name) {
+				v |= ch.channel
+				continue outer
+			}
+		}
+		return fmt.Errorf("invalid channel %q", s)
+	}
+	*c = v
+	return nil
+}
+
+// checkChannels makes sure that the channel data types of common are valid
+func checkChannels(common Common) error {
+	for i, data := range channelData(common) {
+		if !data.Valid() {
+			return fmt.Errorf("invalid channel data %d for %v channel", int(data), channelNames[i].name)
+		}
+	}
+	return nil
+}
+
+// checkChar makes sure that the channel of c is valid
+func checkChar(c Char) error {
+	if !c.Channel.Valid() {
+		return fmt.Errorf("char %d: invalid channel %d", c.Id, int(c.Channel))
+	}
+	return nil
+}
@@ -4,6 +4,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
 )
 
 // CharsetTable enumerates known charset values
@@ -30,6 +38,74 @@ var CharsetTable = map[int]string{
 	0:   "Ansi",
 }
 
+// Charset is the character set of a font that is not unicode.
+// It holds both the value of the binary format and the name of the text and XML formats,
+// so that names and values that are not in CharsetTable are kept as they are.
+// The zero value is the Ansi charset, or no charset for unicode fonts, and is written as an empty name.
+type Charset struct {
+	// Value is the charset byte of the binary format
+	Value uint8
+	// Name is the charset as written in the text and XML formats, e.g. "Russian".
+	// If it is empty the name is looked up from Value.
+	Name string
+}
+
+// NewCharset returns the charset for a value with its name from CharsetTable,
+// or the value as decimal string if it is not in the table
+func NewCharset(value uint8) Charset {
+	name, _ := LookupCharset(int(value))
+	return Charset{Value: value, Name: name}
+}
+
+// ParseCharset returns the charset for a name from CharsetTable or a decimal value.
+// Other names are kept with a value of 0.
+func ParseCharset(name string) Charset {
+	c := Charset{Name: name}
+	if value, found := LookupCharsetValue(name); found && value >= 0 && value <= 0xff {
+		c.Value = uint8(value)
+	}
+	return c
+}
+
+// lookup returns the value of the charset, which is looked up from the name if it is set
+func (c Charset) lookup() (value int, found bool) {
+	if c.Name == "" {
+		return int(c.Value), true
+	}
+	value, found = LookupCharsetValue(c.Name)
+	return value, found && value >= 0 && value <= 0xff
+}
+
+// equal reports whether both charsets refer to the same value
+func (c Charset) equal(other Charset) bool {
+	a, foundA := c.lookup()
+	b, foundB := other.lookup()
+	if foundA && foundB {
+		return a == b
+	}
+	return strings.EqualFold(c.Name, other.Name)
+}
+
+// String returns the name of the charset as it is written in the text and XML formats
+func (c Charset) String() string {
+	if c.Name != "" || c.Value == 0 {
+		return c.Name
+	}
+	name, _ := LookupCharset(int(c.Value))
+	return name
+}
+
+// MarshalText implements encoding.TextMarshaler, which is also used for XML attributes
+func (c Charset) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see ParseCharset
+func (c *Charset) UnmarshalText(text []byte) error {
+	*c = ParseCharset(string(text))
+	return nil
+}
+
 // LookupCharset gets the name of a charset or value as a decimal string when not found
 func LookupCharset(charsetEnum int) (name string, found bool) {
 	if charset, ok := CharsetTable[charsetEnum]; ok {
@@ -51,3 +127,155 @@ func LookupCharsetValue(charset string) (value int, found bool) {
 	value, err := strconv.Atoi(charset)
 	return value, err == nil
 }
+
+// charsetEncodings maps charset values to the Windows code pages that the AngelCode generator uses for them.
+// Default is the system code page, which is assumed to be Windows-1252.
+var charsetEncodings = map[int]encoding.Encoding{
+	0:   charmap.Windows1252,
+	1:   charmap.Windows1252,
+	77:  charmap.Macintosh,
+	128: japanese.ShiftJIS,
+	129: korean.EUCKR,
+	134: simplifiedchinese.GBK,
+	136: traditionalchinese.Big5,
+	161: charmap.Windows1253,
+	162: charmap.Windows1254,
+	163: charmap.Windows1258,
+	177: charmap.Windows1255,
+	178: charmap.Windows1256,
+	186: charmap.Windows1257,
+	204: charmap.Windows1251,
+	222: charmap.Windows874,
+	238: charmap.Windows1250,
+	255: charmap.CodePage437,
+}
+
+// CharsetEncoding returns the encoding of the char ids of fonts with the given charset.
+// The Symbol and Johab charsets are not supported.
+func CharsetEncoding(charset Charset) (encoding.Encoding, error) {
+	value, found := charset.lookup()
+	if !found {
+		return nil, fmt.Errorf("unknown charset %q", charset)
+	}
+	enc, ok := charsetEncodings[value]
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc, nil
+}
+
+// DecodeCharId converts the id of a char in a font with the given charset to a rune.
+// Ids of double byte charsets hold the lead byte in the upper byte.
+func DecodeCharId(charset Charset, id rune) (rune, error) {
+	enc, err := CharsetEncoding(charset)
+	if err != nil {
+		return 0, err
+	}
+	return decodeCharId(enc, id)
+}
+
+func decodeCharId(enc encoding.Encoding, id rune) (rune, error) {
+	var b []byte
+	switch {
+	case id >= 0 && id <= 0xff:
+		b = []byte{byte(id)}
+	case id > 0xff && id <= 0xffff:
+		b = []byte{byte(id >> 8), byte(id)}
+	default:
+		return 0, fmt.Errorf("char id %d is out of range", id)
+	}
+
+	s, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return 0, fmt.Errorf("char id %d: %w", id, err)
+	}
+	r, size := utf8.DecodeRune(s)
+	if r == utf8.RuneError || size != len(s) {
+		return 0, fmt.Errorf("char id %d is not a valid character", id)
+	}
+	return r, nil
+}
+
+// EncodeCharId converts a rune to the id of the char in a font with the given charset
+func EncodeCharId(charset Charset, r rune) (rune, error) {
+	enc, err := CharsetEncoding(charset)
+	if err != nil {
+		return 0, err
+	}
+	return encodeCharId(enc, r)
+}
+
+func encodeCharId(enc encoding.Encoding, r rune) (rune, error) {
+	b, err := enc.NewEncoder().Bytes([]byte(string(r)))
+	if err != nil {
+		return 0, fmt.Errorf("rune %U: %w", r, err)
+	}
+	switch len(b) {
+	case 1:
+		return rune(b[0]), nil
+	case 2:
+		return rune(b[0])<<8 | rune(b[1]), nil
+	}
+	return 0, fmt.Errorf("rune %U is encoded with %d bytes", r, len(b))
+}
+
+// FindChar returns the char of fnt for r.
+// For fonts that are not unicode, r is converted to a char id using Info.Charset.
+func FindChar(fnt *Font, r rune) (Char, bool) {
+	id := r
+	if !fnt.Info.Unicode {
+		var err error
+		if id, err = EncodeCharId(fnt.Info.Charset, r); err != nil {
+			return Char{}, false
+		}
+	}
+	for _, c := range fnt.Chars {
+		if c.Id == id {
+			return c, true
+		}
+	}
+	return Char{}, false
+}
+
+// ToUnicode returns a copy of fnt where the char ids and kerning pairs are converted
+// from Info.Charset to unicode, so that they can be looked up by rune.
+// The invalid char with id -1 is kept. Fonts that are already unicode are returned as a copy.
+func ToUnicode(fnt *Font) (*Font, error) {
+	out := *fnt
+	out.Chars = append([]Char(nil), fnt.Chars...)
+	out.Kernings = append([]Kerning(nil), fnt.Kernings...)
+	out.Pages = append([]Page(nil), fnt.Pages...)
+	if fnt.Info.Unicode {
+		return &out, nil
+	}
+
+	enc, err := CharsetEncoding(fnt.Info.Charset)
+	if err != nil {
+		return nil, err
+	}
+	decode := func(id rune) (rune, error) {
+		if id == -1 {
+			return id, nil
+		}
+		return decodeCharId(enc, id)
+	}
+
+	for i := range out.Chars {
+		if out.Chars[i].Id, err = decode(out.Chars[i].Id); err != nil {
+			return nil, err
+		}
+	}
+	for i := range out.Kernings {
+		k := &out.Kernings[i]
+		if k.First, err = decode(k.First); err != nil {
+			return nil, err
+		}
+		if k.Second, err = decode(k.Second); err != nil {
+			return nil, err
+		}
+	}
+
+	out.Info.Unicode = true
+	out.Info.Charset = Charset{}
+	return &out, nil
+}
@@ -0,0 +1,53 @@
+// Command bmfdiff prints the structural differences between two bmf font files.
+// The files can be in any of the supported formats.
+//
+// Usage:
+//
+//	bmfdiff old.fnt new.fnt
+//
+// The exit status is 0 if the fonts are equal, 1 if they differ and 2 on errors.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Qendolin/go-bmf"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: bmfdiff old.fnt new.fnt")
+		os.Exit(2)
+	}
+
+	a, err := parseFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	b, err := parseFile(os.Args[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	diff := bmf.Diff(a, b)
+	if diff.Empty() {
+		return
+	}
+	fmt.Print(diff)
+	os.Exit(1)
+}
+
+func parseFile(name string) (*bmf.Font, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	fnt, err := bmf.ParseBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+	return fnt, nil
+}
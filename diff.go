@@ -0,0 +1,278 @@
+package bmf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind specifies how an entry differs between two fonts
+type ChangeKind int
+
+// Change kinds
+const (
+	Changed ChangeKind = iota
+	Added
+	Removed
+)
+
+// FieldChange describes a field with a different value.
+// Field is named like the attribute in the text and XML formats.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%v %v → %v", c.Field, c.Old, c.New)
+}
+
+// CharChange describes a char that was added, removed or changed
+type CharChange struct {
+	Id     rune
+	Kind   ChangeKind
+	Fields []FieldChange
+}
+
+// KerningChange describes a kerning pair that was added, removed or changed
+type KerningChange struct {
+	First  rune
+	Second rune
+	Kind   ChangeKind
+	Old    int
+	New    int
+}
+
+// PageChange describes a page that was added, removed or changed
+type PageChange struct {
+	Id   int
+	Kind ChangeKind
+	Old  string
+	New  string
+}
+
+// FontDiff lists the structural differences between two fonts
+type FontDiff struct {
+	Info          []FieldChange
+	Common        []FieldChange
+	DistanceField []FieldChange
+	Pages         []PageChange
+	Chars         []CharChange
+	Kernings      []KerningChange
+}
+
+// Diff compares a to b.
+// Chars are matched by id, kerning pairs by their chars and pages by id,
+// so the order of the entries does not matter.
+func Diff(a, b *Font) *FontDiff {
+	d := &FontDiff{
+		Info:          diffFields(a.Info, b.Info),
+		Common:        diffFields(a.Common, b.Common),
+		DistanceField: diffFields(distanceField(a), distanceField(b)),
+	}
+
+	pagesA, pagesB := map[int]Page{}, map[int]Page{}
+	for _, p := range a.Pages {
+		pagesA[p.Id] = p
+	}
+	for _, p := range b.Pages {
+		pagesB[p.Id] = p
+	}
+	for _, id := range unionKeys(pagesA, pagesB, func(x, y int) bool { return x < y }) {
+		pa, inA := pagesA[id]
+		pb, inB := pagesB[id]
+		switch {
+		case !inB:
+			d.Pages = append(d.Pages, PageChange{Id: id, Kind: Removed, Old: pa.File})
+		case !inA:
+			d.Pages = append(d.Pages, PageChange{Id: id, Kind: Added, New: pb.File})
+		case pa.File != pb.File:
+			d.Pages = append(d.Pages, PageChange{Id: id, Kind: Changed, Old: pa.File, New: pb.File})
+		}
+	}
+
+	charsA, charsB := map[rune]Char{}, map[rune]Char{}
+	for _, c := range a.Chars {
+		charsA[c.Id] = c
+	}
+	for _, c := range b.Chars {
+		charsB[c.Id] = c
+	}
+	for _, id := range unionKeys(charsA, charsB, func(x, y rune) bool { return x < y }) {
+		ca, inA := charsA[id]
+		cb, inB := charsB[id]
+		switch {
+		case !inB:
+			d.Chars = append(d.Chars, CharChange{Id: id, Kind: Removed})
+		case !inA:
+			d.Chars = append(d.Chars, CharChange{Id: id, Kind: Added})
+		default:
+			if fields := diffFields(ca, cb); len(fields) > 0 {
+				d.Chars = append(d.Chars, CharChange{Id: id, Kind: Changed, Fields: fields})
+			}
+		}
+	}
+
+	kernA, kernB := map[[2]rune]int{}, map[[2]rune]int{}
+	for _, k := range a.Kernings {
+		kernA[[2]rune{k.First, k.Second}] = k.Amount
+	}
+	for _, k := range b.Kernings {
+		kernB[[2]rune{k.First, k.Second}] = k.Amount
+	}
+	for _, pair := range unionKeys(kernA, kernB, func(x, y [2]rune) bool {
+		return x[0] < y[0] || x[0] == y[0] && x[1] < y[1]
+	}) {
+		ka, inA := kernA[pair]
+		kb, inB := kernB[pair]
+		change := KerningChange{First: pair[0], Second: pair[1], Old: ka, New: kb}
+		switch {
+		case !inB:
+			change.Kind = Removed
+		case !inA:
+			change.Kind = Added
+		case ka != kb:
+			change.Kind = Changed
+		default:
+			continue
+		}
+		d.Kernings = append(d.Kernings, change)
+	}
+
+	return d
+}
+
+// distanceField returns the distance field settings of fnt or the zero value if it has none
+func distanceField(fnt *Font) DistanceField {
+	if fnt.DistanceField == nil {
+		return DistanceField{}
+	}
+	return *fnt.DistanceField
+}
+
+// unionKeys returns the keys of both maps sorted by less
+func unionKeys[K comparable, V any](a, b map[K]V, less func(x, y K) bool) []K {
+	keys := make([]K, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
+
+// diffFields compares two structs of the same type field by field
+func diffFields(a, b interface{}) []FieldChange {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	typ := va.Type()
+
+	var changes []FieldChange
+	for i := 0; i < typ.NumField(); i++ {
+		fa, fb := va.Field(i).Interface(), vb.Field(i).Interface()
+		if fa == fb {
+			continue
+		}
+		name := strings.Split(typ.Field(i).Tag.Get("xml"), ",")[0]
+		changes = append(changes, FieldChange{
+			Field: name,
+			Old:   formatField(fa),
+			New:   formatField(fb),
+		})
+	}
+	return changes
+}
+
+// formatField formats a value like it appears in the text and XML formats
+func formatField(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String()
+	}
+	if m, ok := v.(xml.MarshalerAttr); ok {
+		if attr, err := m.MarshalXMLAttr(xml.Name{}); err == nil {
+			return attr.Value
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// formatId formats a char id as a unicode code point
+func formatId(id rune) string {
+	if id < 0 {
+		return strconv.Itoa(int(id))
+	}
+	return fmt.Sprintf("U+%04X", id)
+}
+
+func formatFields(fields []FieldChange) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Empty reports whether the fonts are equal
+func (d *FontDiff) Empty() bool {
+	return len(d.Info) == 0 && len(d.Common) == 0 && len(d.DistanceField) == 0 && len(d.Pages) == 0 && len(d.Chars) == 0 && len(d.Kernings) == 0
+}
+
+// String formats the differences with one line per changed entry,
+// e.g. "char U+0041: xadvance 19 → 20"
+func (d *FontDiff) String() string {
+	sb := &strings.Builder{}
+
+	if len(d.Info) > 0 {
+		fmt.Fprintf(sb, "info: %v\n", formatFields(d.Info))
+	}
+	if len(d.Common) > 0 {
+		fmt.Fprintf(sb, "common: %v\n", formatFields(d.Common))
+	}
+	if len(d.DistanceField) > 0 {
+		fmt.Fprintf(sb, "distanceField: %v\n", formatFields(d.DistanceField))
+	}
+	for _, p := range d.Pages {
+		switch p.Kind {
+		case Added:
+			fmt.Fprintf(sb, "+ page %d: %q\n", p.Id, p.New)
+		case Removed:
+			fmt.Fprintf(sb, "- page %d: %q\n", p.Id, p.Old)
+		default:
+			fmt.Fprintf(sb, "page %d: %q → %q\n", p.Id, p.Old, p.New)
+		}
+	}
+	for _, c := range d.Chars {
+		switch c.Kind {
+		case Added:
+			fmt.Fprintf(sb, "+ char %v\n", formatId(c.Id))
+		case Removed:
+			fmt.Fprintf(sb, "- char %v\n", formatId(c.Id))
+		default:
+			fmt.Fprintf(sb, "char %v: %v\n", formatId(c.Id), formatFields(c.Fields))
+		}
+	}
+	for _, k := range d.Kernings {
+		switch k.Kind {
+		case Added:
+			fmt.Fprintf(sb, "+ kerning %v %v: %d\n", formatId(k.First), formatId(k.Second), k.New)
+		case Removed:
+			fmt.Fprintf(sb, "- kerning %v %v: %d\n", formatId(k.First), formatId(k.Second), k.Old)
+		default:
+			fmt.Fprintf(sb, "kerning %v %v: %d → %d\n", formatId(k.First), formatId(k.Second), k.Old, k.New)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,187 @@
+package bmf
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// channelOrder lists the channel bits in the order of the components of a color.NRGBA
+var channelOrder = [4]Channel{Red, Green, Blue, Alpha}
+
+// channelData returns the data types of the color channels in the order of channelOrder
+func channelData(common Common) [4]ChannelData {
+	return [4]ChannelData{common.RedChannel, common.GreenChannel, common.BlueChannel, common.AlphaChannel}
+}
+
+func setChannelData(common *Common, data [4]ChannelData) {
+	common.RedChannel, common.GreenChannel, common.BlueChannel, common.AlphaChannel = data[0], data[1], data[2], data[3]
+}
+
+// EffectOptions selects the effect that BakeEffect draws
+type EffectOptions struct {
+	// Thickness grows the glyph by the given number of pixels, like the outline thickness of the AngelCode generator
+	Thickness int
+	// Offset moves the effect relative to the glyph, e.g. (2, 2) for a drop shadow to the bottom right
+	Offset image.Point
+	// Channel selects the color channels that hold the effect. It defaults to Alpha.
+	Channel Channel
+	// Combine keeps the glyph in the effect channels, which then hold GlyphAndOutline.
+	// The effect alone takes the values up to 127 and the glyph the values from 128.
+	Combine bool
+}
+
+// BakeEffect draws an outline or drop shadow around the glyphs of fnt into the channels selected by opts.
+// The glyph rectangles grow to make room for the effect, moving the glyphs by XOffset and YOffset
+// so that they stay in place, and the growth is added to Info.Padding. Info.Outline is set to the thickness.
+// If the effect replaces the only channels that hold the glyph, the channels that were set to Zero or One
+// hold the glyph instead, like the outline preset of the AngelCode generator.
+// It returns an updated copy of fnt along with new pages of the same size.
+func BakeEffect(fnt *Font, pages []*image.NRGBA, opts EffectOptions) (*Font, []*image.NRGBA, error) {
+	if err := checkGlyphs(fnt, pages); err != nil {
+		return nil, nil, err
+	}
+	if opts.Thickness < 0 {
+		return nil, nil, fmt.Errorf("invalid thickness %d", opts.Thickness)
+	}
+	if opts.Channel == 0 {
+		opts.Channel = Alpha
+	}
+	if opts.Channel&^All != 0 {
+		return nil, nil, fmt.Errorf("invalid channel %d", opts.Channel)
+	}
+	if fnt.Common.ScaleW <= 0 || fnt.Common.ScaleH <= 0 {
+		return nil, nil, fmt.Errorf("invalid page size %dx%d", fnt.Common.ScaleW, fnt.Common.ScaleH)
+	}
+
+	grow := Padding{
+		Up:    opts.Thickness + max0(-opts.Offset.Y),
+		Right: opts.Thickness + max0(opts.Offset.X),
+		Down:  opts.Thickness + max0(opts.Offset.Y),
+		Left:  opts.Thickness + max0(-opts.Offset.X),
+	}
+
+	out := *fnt
+	out.Info.Padding.Up += grow.Up
+	out.Info.Padding.Right += grow.Right
+	out.Info.Padding.Down += grow.Down
+	out.Info.Padding.Left += grow.Left
+	if opts.Thickness > 0 {
+		out.Info.Outline = opts.Thickness
+	}
+
+	oldData := channelData(fnt.Common)
+	newData := oldData
+	glyphKept := opts.Combine
+	for i, ch := range channelOrder {
+		switch {
+		case opts.Channel&ch != 0 && opts.Combine:
+			newData[i] = GlyphAndOutline
+		case opts.Channel&ch != 0:
+			newData[i] = Outline
+		case oldData[i] == Glyph || oldData[i] == GlyphAndOutline:
+			glyphKept = true
+		}
+	}
+	if !glyphKept {
+		for i, ch := range channelOrder {
+			if opts.Channel&ch == 0 && (oldData[i] == Zero || oldData[i] == One) {
+				newData[i] = Glyph
+			}
+		}
+	}
+	setChannelData(&out.Common, newData)
+
+	out.Chars = append([]Char(nil), fnt.Chars...)
+	out.Kernings = append([]Kerning(nil), fnt.Kernings...)
+	for i := range out.Chars {
+		c := &out.Chars[i]
+		if c.Width <= 0 || c.Height <= 0 {
+			continue
+		}
+		c.Width += grow.Left + grow.Right
+		c.Height += grow.Up + grow.Down
+		c.XOffset -= grow.Left
+		c.YOffset -= grow.Up
+	}
+
+	images, err := newPages(&out, fnt.Common.ScaleW, fnt.Common.ScaleH, pageFilePattern(fnt))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e := effect{opts: opts, grow: grow, oldData: oldData, newData: newData}
+	for i, c := range out.Chars {
+		if c.Width <= 0 || c.Height <= 0 {
+			continue
+		}
+		src := fnt.Chars[i]
+		e.draw(images[c.Page], c, pages[src.Page], src, glyphChannel(fnt, src))
+	}
+
+	return &out, images, nil
+}
+
+func max0(v int) int {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+// effect draws the glyphs for BakeEffect
+type effect struct {
+	opts             EffectOptions
+	grow             Padding
+	oldData, newData [4]ChannelData
+}
+
+// draw copies the glyph src from srcImg to the rectangle of c on dst and adds the effect.
+// channel is the index of the color component that holds the glyph in srcImg.
+func (e effect) draw(dst *image.NRGBA, c Char, srcImg *image.NRGBA, src Char, channel int) {
+	srcOrigin := srcImg.Bounds().Min.Add(image.Pt(src.X, src.Y))
+	inner := image.Rect(e.grow.Left, e.grow.Up, e.grow.Left+src.Width, e.grow.Up+src.Height)
+
+	glyph := image.NewAlpha(image.Rect(0, 0, c.Width, c.Height))
+	for y := 0; y < src.Height; y++ {
+		for x := 0; x < src.Width; x++ {
+			v := channelValue(srcImg.NRGBAAt(srcOrigin.X+x, srcOrigin.Y+y), channel)
+			glyph.SetAlpha(inner.Min.X+x, inner.Min.Y+y, color.Alpha{A: v})
+		}
+	}
+
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			g := glyph.AlphaAt(x, y).A
+			fx := dilate(glyph, x-e.opts.Offset.X, y-e.opts.Offset.Y, e.opts.Thickness)
+
+			var old color.NRGBA
+			inside := image.Pt(x, y).In(inner)
+			if inside {
+				old = srcImg.NRGBAAt(srcOrigin.X+x-inner.Min.X, srcOrigin.Y+y-inner.Min.Y)
+			}
+			oldPix := [4]uint8{old.R, old.G, old.B, old.A}
+
+			i := dst.PixOffset(c.X+x, c.Y+y)
+			pix := dst.Pix[i : i+4 : i+4]
+			for j, ch := range channelOrder {
+				switch {
+				case e.opts.Channel&ch != 0 && e.opts.Combine:
+					// the glyph is drawn over the effect, which is scaled to the lower half
+					under := int(fx) * 127 / 255
+					pix[j] = uint8(under + (255-under)*int(g)/255)
+				case e.opts.Channel&ch != 0:
+					pix[j] = fx
+				case e.newData[j] != e.oldData[j]:
+					pix[j] = g
+				case inside:
+					pix[j] = oldPix[j]
+				case e.oldData[j] == One:
+					pix[j] = 0xff
+				default:
+					pix[j] = 0
+				}
+			}
+		}
+	}
+}
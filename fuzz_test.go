@@ -0,0 +1,69 @@
+package bmf_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Qendolin/go-bmf"
+	"github.com/stretchr/testify/require"
+)
+
+// addSeeds adds all font files in testdata to the seed corpus
+func addSeeds(f *testing.F) {
+	files, err := filepath.Glob("./testdata/*.fnt")
+	require.NoError(f, err)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoErrorf(f, err, "Unable to read testdata")
+		f.Add(data)
+	}
+}
+
+// fuzzParse checks that parsing data from a reader and in place gives the same result
+func fuzzParse(f *testing.F, parse func(io.Reader) (*bmf.Font, error), parseBytes func([]byte) (*bmf.Font, error)) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fnt, err := parse(bytes.NewReader(data))
+		fntBytes, errBytes := parseBytes(data)
+		if (err == nil) != (errBytes == nil) {
+			t.Fatalf("reader error %v, bytes error %v", err, errBytes)
+		}
+		if err == nil {
+			assertFontEqual(t, *fnt, *fntBytes)
+		}
+	})
+}
+
+func FuzzParseText(f *testing.F) {
+	fuzzParse(f, bmf.ParseText, bmf.ParseTextBytes)
+}
+
+func FuzzParseXML(f *testing.F) {
+	fuzzParse(f, bmf.ParseXML, bmf.ParseXMLBytes)
+}
+
+func FuzzParseBinary(f *testing.F) {
+	fuzzParse(f, bmf.ParseBinary, bmf.ParseBinaryBytes)
+}
+
+func FuzzParse(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fnt, err := bmf.Parse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		// anything that parses must survive a round trip through the binary format
+		buf := &bytes.Buffer{}
+		if err := bmf.SerializeBinary(fnt, buf); err != nil {
+			return
+		}
+		if _, err := bmf.ParseBinary(buf); err != nil {
+			t.Fatalf("unable to parse serialized font: %v", err)
+		}
+	})
+}
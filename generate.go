@@ -0,0 +1,297 @@
+package bmf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// GenerateOptions holds the settings of Generate.
+// They correspond to the settings of the AngelCode generator, see testdata/test.bmfc.
+type GenerateOptions struct {
+	// Runes are the chars to include. Runes that the font has no glyph for are skipped.
+	// It defaults to the printable ASCII chars.
+	Runes []rune
+	// Size is the size of an em in pixels, like a negative fontSize in the AngelCode generator.
+	// GenerateFace only records it in Info.Size and defaults it to the ascent plus descent of the face.
+	Size int
+	// Hinting selects how the glyph outlines and metrics are quantized to pixels.
+	// It is ignored by GenerateFace, where the face was created with its own hinting.
+	Hinting font.Hinting
+	// Padding is added around each glyph
+	Padding Padding
+	// Spacing is kept free between the glyphs on the pages
+	Spacing Spacing
+	// Outline is the thickness of the outline in pixels.
+	// When it is not zero, the alpha channel holds the outline and the color channels the glyph.
+	Outline int
+	// Width and Height of the pages in pixels. They default to 256.
+	Width, Height int
+	// PageFile is the fmt format for the page file names, given the page id.
+	// It defaults to "page_%d.png".
+	PageFile string
+	// SkipKernings disables the extraction of kerning pairs
+	SkipKernings bool
+	// KerningRunes are the chars whose pairs are queried for kerning, which takes a query for every
+	// pair of them. Runes without a generated glyph are ignored.
+	// It defaults to the printable ASCII chars.
+	KerningRunes []rune
+}
+
+// Generate rasterizes the glyphs of a TrueType or OpenType font and packs them onto pages.
+// The kerning pairs of GenerateOptions.KerningRunes are read from the GPOS or kern table.
+// It returns the font along with the page images, which can be written with SavePages.
+func Generate(src []byte, opts GenerateOptions) (*Font, []*image.NRGBA, error) {
+	otf, err := opentype.Parse(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Size <= 0 {
+		return nil, nil, fmt.Errorf("invalid size %d", opts.Size)
+	}
+
+	face, err := opentype.NewFace(otf, &opentype.FaceOptions{
+		Size:    float64(opts.Size),
+		DPI:     72,
+		Hinting: opts.Hinting,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer face.Close()
+
+	// opentype.Face.Kern does not scale by the face size, so the pairs are read from the font itself
+	buf := &sfnt.Buffer{}
+	ppem := fixed.I(opts.Size)
+	kern := func(first, second rune) fixed.Int26_6 {
+		x0, err0 := otf.GlyphIndex(buf, first)
+		x1, err1 := otf.GlyphIndex(buf, second)
+		if err0 != nil || err1 != nil {
+			return 0
+		}
+		amount, err := otf.Kern(buf, x0, x1, ppem, opts.Hinting)
+		if err != nil {
+			return 0
+		}
+		return amount
+	}
+
+	fnt, pages, err := generate(face, kern, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if name, err := otf.Name(buf, sfnt.NameIDFamily); err == nil {
+		fnt.Info.Face = name
+	} else if !errors.Is(err, sfnt.ErrNotFound) {
+		return nil, nil, err
+	}
+	if post := otf.PostTable(); post != nil {
+		fnt.Info.Italic = post.ItalicAngle != 0
+	}
+	return fnt, pages, nil
+}
+
+// GenerateFace draws the glyphs of face and packs them onto pages.
+// The glyph bounds, advances and kerning pairs are queried from the face, which allows to
+// create fonts at runtime, e.g. from a scaled opentype face or a procedural one.
+// Info.Face is left empty since faces have no name.
+func GenerateFace(face font.Face, opts GenerateOptions) (*Font, []*image.NRGBA, error) {
+	if opts.Size < 0 {
+		return nil, nil, fmt.Errorf("invalid size %d", opts.Size)
+	}
+	if opts.Size == 0 {
+		metrics := face.Metrics()
+		opts.Size = (metrics.Ascent + metrics.Descent).Ceil()
+	}
+	return generate(face, face.Kern, opts)
+}
+
+// generate draws the glyphs of face and packs them onto pages
+func generate(face font.Face, kern func(first, second rune) fixed.Int26_6, opts GenerateOptions) (*Font, []*image.NRGBA, error) {
+	if opts.Width == 0 {
+		opts.Width = 256
+	}
+	if opts.Height == 0 {
+		opts.Height = 256
+	}
+	if opts.Width < 0 || opts.Height < 0 {
+		return nil, nil, fmt.Errorf("invalid page size %dx%d", opts.Width, opts.Height)
+	}
+	if opts.Outline < 0 {
+		return nil, nil, fmt.Errorf("invalid outline thickness %d", opts.Outline)
+	}
+	if opts.PageFile == "" {
+		opts.PageFile = "page_%d.png"
+	}
+	runes := opts.Runes
+	if runes == nil {
+		for r := rune(0x20); r <= 0x7e; r++ {
+			runes = append(runes, r)
+		}
+	}
+
+	metrics := face.Metrics()
+	fnt := &Font{
+		Info: Info{
+			Size:     -opts.Size,
+			Unicode:  true,
+			StretchH: 100,
+			Smooth:   true,
+			AA:       1,
+			Padding:  opts.Padding,
+			Spacing:  opts.Spacing,
+			Outline:  opts.Outline,
+		},
+		Common: Common{
+			LineHeight:   metrics.Height.Ceil(),
+			Base:         metrics.Ascent.Ceil(),
+			ScaleW:       opts.Width,
+			ScaleH:       opts.Height,
+			AlphaChannel: Glyph,
+			RedChannel:   One,
+			GreenChannel: One,
+			BlueChannel:  One,
+		},
+	}
+	if opts.Outline > 0 {
+		fnt.Common.AlphaChannel = Outline
+		fnt.Common.RedChannel = Glyph
+		fnt.Common.GreenChannel = Glyph
+		fnt.Common.BlueChannel = Glyph
+	}
+
+	// the glyph masks include the padding and outline
+	var masks []*image.Alpha
+	border := image.Pt(opts.Padding.Left+opts.Outline, opts.Padding.Up+opts.Outline)
+	seen := map[rune]bool{}
+	for _, r := range runes {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+
+		dr, mask, maskp, advance, ok := face.Glyph(fixed.Point26_6{}, r)
+		if !ok {
+			continue
+		}
+		c := Char{Id: r, XAdvance: advance.Round(), Channel: All}
+		var glyph *image.Alpha
+		dr, maskp = trimGlyph(dr, mask, maskp)
+		if !dr.Empty() {
+			c.Width = dr.Dx() + opts.Padding.Left + opts.Padding.Right + 2*opts.Outline
+			c.Height = dr.Dy() + opts.Padding.Up + opts.Padding.Down + 2*opts.Outline
+			c.XOffset = dr.Min.X - border.X
+			c.YOffset = fnt.Common.Base + dr.Min.Y - border.Y
+			// faces may reuse the mask for the next glyph
+			glyph = image.NewAlpha(image.Rect(0, 0, c.Width, c.Height))
+			draw.Draw(glyph, image.Rectangle{Min: border, Max: border.Add(dr.Size())}, mask, maskp, draw.Src)
+		}
+		fnt.Chars = append(fnt.Chars, c)
+		masks = append(masks, glyph)
+	}
+
+	pages, err := newPages(fnt, opts.Width, opts.Height, opts.PageFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, c := range fnt.Chars {
+		if masks[i] == nil {
+			continue
+		}
+		drawGlyph(pages[c.Page], image.Pt(c.X, c.Y), masks[i], opts.Outline)
+	}
+
+	if !opts.SkipKernings && kern != nil {
+		fnt.Kernings = generateKernings(fnt.Chars, kern, opts.KerningRunes)
+	}
+
+	return fnt, pages, nil
+}
+
+// generateKernings queries kern for every pair of the chars that are in runes or,
+// if runes is nil, in the printable ASCII range. The number of queries grows with the
+// square of the runes, so they are limited instead of pairing all chars of large fonts.
+func generateKernings(chars []Char, kern func(first, second rune) fixed.Int26_6, runes []rune) []Kerning {
+	selected := map[rune]bool{}
+	for _, r := range runes {
+		selected[r] = true
+	}
+
+	var ids []rune
+	for _, c := range chars {
+		if selected[c.Id] || (runes == nil && c.Id >= 0x20 && c.Id <= 0x7e) {
+			ids = append(ids, c.Id)
+		}
+	}
+
+	var kernings []Kerning
+	for _, first := range ids {
+		for _, second := range ids {
+			if amount := kern(first, second).Round(); amount != 0 {
+				kernings = append(kernings, Kerning{First: first, Second: second, Amount: amount})
+			}
+		}
+	}
+	return kernings
+}
+
+// trimGlyph shrinks dr to the pixels of mask that are not transparent,
+// so that blank glyphs like spaces take no room on the pages
+func trimGlyph(dr image.Rectangle, mask image.Image, maskp image.Point) (image.Rectangle, image.Point) {
+	ink := image.Rectangle{}
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			if _, _, _, a := mask.At(maskp.X+x, maskp.Y+y).RGBA(); a == 0 {
+				continue
+			}
+			ink = ink.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return ink.Add(dr.Min), maskp.Add(ink.Min)
+}
+
+// drawGlyph draws a glyph mask onto a page at pos.
+// With an outline, the alpha channel holds the glyph grown by outline pixels
+// and the color channels hold the glyph, otherwise the glyph is white.
+func drawGlyph(page *image.NRGBA, pos image.Point, glyph *image.Alpha, outline int) {
+	size := glyph.Bounds().Size()
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			v := glyph.AlphaAt(x, y).A
+			i := page.PixOffset(pos.X+x, pos.Y+y)
+			pix := page.Pix[i : i+4 : i+4]
+			if outline == 0 {
+				pix[0], pix[1], pix[2], pix[3] = 0xff, 0xff, 0xff, v
+				continue
+			}
+			pix[0], pix[1], pix[2], pix[3] = v, v, v, dilate(glyph, x, y, outline)
+		}
+	}
+}
+
+// dilate returns the largest value of glyph within radius of x,y
+func dilate(glyph *image.Alpha, x, y, radius int) uint8 {
+	var max uint8
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if dx*dx+dy*dy > radius*radius {
+				continue
+			}
+			if !(image.Point{X: x + dx, Y: y + dy}).In(glyph.Rect) {
+				continue
+			}
+			if v := glyph.AlphaAt(x+dx, y+dy).A; v > max {
+				max = v
+			}
+		}
+	}
+	return max
+}
@@ -1,184 +1,259 @@
 package binary
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"io"
 )
 
+// Reader decodes values from Buf.
+// Buf can be set directly or filled in bulk from Src using Fill.
 type Reader struct {
 	Order binary.ByteOrder
 	Src   io.Reader
+	Buf   []byte
 	Index int
 	Err   error
-	buf   []byte
+	off   int
+	data  []byte
 }
 
 var ErrNotNullTerminated = errors.New("string was not null terminated")
 
-func (br *Reader) Read(n int) (ok bool) {
+// fillChunk limits how much Fill allocates ahead of the data actually read,
+// so a bogus length does not reserve memory the source cannot fill
+const fillChunk = 1 << 16
+
+// Fill reads exactly n bytes from Src into Buf, discarding any unread bytes.
+// The previous contents of Buf are invalidated.
+// Without a Src it only checks that at least n bytes are left in Buf.
+func (br *Reader) Fill(n int) (ok bool) {
 	if br.Err != nil {
 		return false
 	}
 
-	if cap(br.buf) <= n {
-		br.buf = make([]byte, n)
-	} else {
-		br.buf = br.buf[:n]
+	if br.Src == nil {
+		if br.Len() >= n {
+			return true
+		}
+		if br.Len() == 0 {
+			br.Err = io.EOF
+		} else {
+			br.Err = io.ErrUnexpectedEOF
+		}
+		return false
 	}
 
-	nread, err := br.Src.Read(br.buf)
-	if err != nil {
-		br.Err = err
-		ok = false
+	br.off = 0
+	br.data = br.data[:0]
+	for len(br.data) < n {
+		start := len(br.data)
+		end := start + fillChunk
+		if end > n {
+			end = n
+		}
+		if cap(br.data) < end {
+			grown := make([]byte, start, 2*cap(br.data)+end-start)
+			copy(grown, br.data)
+			br.data = grown
+		}
+		br.data = br.data[:end]
+
+		nread, err := io.ReadFull(br.Src, br.data[start:])
+		if err != nil {
+			br.data = br.data[:start+nread]
+			if errors.Is(err, io.EOF) && start > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			br.Buf = br.data
+			br.Err = err
+			return false
+		}
 	}
 
-	br.Index += nread
+	br.Buf = br.data
+	return true
+}
+
+// Len returns the number of unread bytes in Buf
+func (br *Reader) Len() int {
+	return len(br.Buf) - br.off
+}
+
+// Read consumes the next n bytes of Buf.
+// The returned slice is only valid until the next call to Fill.
+func (br *Reader) Read(n int) (b []byte, ok bool) {
+	if br.Err != nil {
+		return nil, false
+	}
+	if n < 0 || br.Len() < n {
+		br.Err = io.ErrUnexpectedEOF
+		return nil, false
+	}
 
-	return br.Err == nil
+	b = br.Buf[br.off : br.off+n]
+	br.off += n
+	br.Index += n
+	return b, true
 }
 
 func (br *Reader) ReadUInt8(i *int) (ok bool) {
-	if !br.Read(1) {
-		return false
+	b, ok := br.Read(1)
+	if ok {
+		*i = int(b[0])
 	}
-	*i = int(br.buf[0])
-	return true
+	return ok
 }
 
 func (br *Reader) ReadUInt16(i *int) (ok bool) {
-	if !br.Read(2) {
-		return false
+	b, ok := br.Read(2)
+	if ok {
+		*i = int(br.Order.Uint16(b))
 	}
-	*i = int(br.Order.Uint16(br.buf))
-	return true
+	return ok
 }
 
 func (br *Reader) ReadInt16(i *int) (ok bool) {
-	if !br.Read(2) {
-		return false
+	b, ok := br.Read(2)
+	if ok {
+		*i = int(int16(br.Order.Uint16(b)))
 	}
-	*i = int(int16(br.Order.Uint16(br.buf)))
-	return true
+	return ok
 }
 
 func (br *Reader) ReadUInt32(i *int) (ok bool) {
-	if !br.Read(4) {
-		return false
+	b, ok := br.Read(4)
+	if ok {
+		*i = int(br.Order.Uint32(b))
 	}
-	*i = int(br.Order.Uint32(br.buf))
-	return true
+	return ok
 }
 
 func (br *Reader) ReadBits(i *uint8) (ok bool) {
-	if !br.Read(1) {
-		return false
+	b, ok := br.Read(1)
+	if ok {
+		*i = b[0]
 	}
-	*i = br.buf[0]
-	return true
+	return ok
 }
 
-func (br *Reader) ReadRune(r *rune) (ok bool) {
-	if !br.Read(4) {
-		return false
+func (br *Reader) ReadInt32(r *int32) (ok bool) {
+	b, ok := br.Read(4)
+	if ok {
+		*r = int32(br.Order.Uint32(b))
 	}
-	*r = rune(int32(br.Order.Uint32(br.buf)))
-	return true
+	return ok
 }
 
+// ReadNullString reads a null terminated string of at most max bytes including the terminator
 func (br *Reader) ReadNullString(s *string, max int) (ok bool) {
-	buf := make([]byte, max)
-	i := 0
+	if br.Err != nil {
+		return false
+	}
 
-	for ; i <= max; i++ {
-		if i == max {
+	search := br.Buf[br.off:]
+	if len(search) > max {
+		search = search[:max]
+	}
+	i := bytes.IndexByte(search, 0)
+	if i < 0 {
+		if len(search) < max {
+			br.Err = io.ErrUnexpectedEOF
+		} else {
 			br.Err = ErrNotNullTerminated
-			return false
-		}
-
-		if !br.Read(1) {
-			return false
-		}
-
-		if br.buf[0] == 0 {
-			break
 		}
-
-		buf[i] = br.buf[0]
+		return false
 	}
 
-	*s = string(buf[0:i])
-
+	b, _ := br.Read(i + 1)
+	*s = string(b[:i])
 	return true
 }
 
 func (br *Reader) ReadString(s *string, c int) (ok bool) {
-	if !br.Read(c) {
-		return false
+	b, ok := br.Read(c)
+	if ok {
+		*s = string(b)
 	}
-	*s = string(br.buf)
-	return true
+	return ok
 }
 
+// Writer encodes values into an internal buffer which is written to Dst by Flush
 type Writer struct {
-	Order binary.ByteOrder
-	Dst   io.Writer
-	Err   error
+	Order   binary.ByteOrder
+	Dst     io.Writer
+	Err     error
+	buf     []byte
+	scratch [4]byte
 }
 
-func (bw *Writer) Write(p []byte) (ok bool) {
+// Flush writes all buffered bytes to Dst.
+// The buffer is reused for subsequent writes.
+func (bw *Writer) Flush() (ok bool) {
 	if bw.Err != nil {
 		return false
 	}
 
-	_, err := bw.Dst.Write(p)
+	_, err := bw.Dst.Write(bw.buf)
+	bw.buf = bw.buf[:0]
 	if err != nil {
 		bw.Err = err
-		ok = false
+		return false
 	}
-	return
+	return true
+}
+
+func (bw *Writer) Write(p []byte) (ok bool) {
+	if bw.Err != nil {
+		return false
+	}
+	bw.buf = append(bw.buf, p...)
+	return true
 }
 
 func (bw *Writer) WriteString(s string) (ok bool) {
-	return bw.Write([]byte(s))
+	if bw.Err != nil {
+		return false
+	}
+	bw.buf = append(bw.buf, s...)
+	return true
 }
 
 func (bw *Writer) WriteNullString(s string) (ok bool) {
-	return bw.Write(append([]byte(s), 0))
+	return bw.WriteString(s) && bw.WriteUInt8(0)
 }
 
 func (bw *Writer) WriteBits(b uint8) (ok bool) {
-	return bw.Write([]byte{b})
+	return bw.WriteUInt8(b)
 }
 
 func (bw *Writer) WriteUInt8(i uint8) (ok bool) {
-	return bw.Write([]byte{i})
+	if bw.Err != nil {
+		return false
+	}
+	bw.buf = append(bw.buf, i)
+	return true
 }
 
 func (bw *Writer) WriteInt8(i int8) (ok bool) {
-	return bw.Write([]byte{byte(i)})
+	return bw.WriteUInt8(uint8(i))
 }
 
 func (bw *Writer) WriteInt16(i int16) (ok bool) {
-	buf := make([]byte, 2)
-	bw.Order.PutUint16(buf, uint16(i))
-	return bw.Write(buf)
+	return bw.WriteUInt16(uint16(i))
 }
 
 func (bw *Writer) WriteUInt16(i uint16) (ok bool) {
-	buf := make([]byte, 2)
-	bw.Order.PutUint16(buf, i)
-	return bw.Write(buf)
+	bw.Order.PutUint16(bw.scratch[:2], i)
+	return bw.Write(bw.scratch[:2])
 }
 
 func (bw *Writer) WriteInt32(i int32) (ok bool) {
-	buf := make([]byte, 4)
-	bw.Order.PutUint32(buf, uint32(i))
-	return bw.Write(buf)
+	return bw.WriteUInt32(uint32(i))
 }
 
 func (bw *Writer) WriteUInt32(i uint32) (ok bool) {
-	buf := make([]byte, 4)
-	bw.Order.PutUint32(buf, i)
-	return bw.Write(buf)
+	bw.Order.PutUint32(bw.scratch[:4], i)
+	return bw.Write(bw.scratch[:4])
 }
@@ -0,0 +1,18 @@
+package bmf
+
+import "encoding/json"
+
+// jsonFont has the fields of Font without its methods, so that it is encoded as object
+type jsonFont Font
+
+// MarshalJSON implements json.Marshaler.
+// The font is encoded as object with the attribute names of the XML format as keys,
+// instead of the text format that MarshalText would produce.
+func (font Font) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFont(font))
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the objects written by MarshalJSON
+func (font *Font) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*jsonFont)(font))
+}
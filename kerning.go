@@ -0,0 +1,315 @@
+package bmf
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// KerningTable is a compact, read-only lookup structure for kerning pairs.
+// The pairs are grouped by their first char and stored in sorted arrays,
+// which takes 6 bytes per pair compared to 16 for a Kerning,
+// and a lookup is two binary searches.
+type KerningTable struct {
+	// firsts holds the sorted unique first chars
+	firsts []rune
+	// offsets[i] to offsets[i+1] is the range in seconds and amounts for firsts[i]
+	offsets []int32
+	seconds []rune
+	amounts []int16
+}
+
+// NewKerningTable builds a table from kernings.
+// Of duplicate pairs the last one is kept. Amounts are clamped to the range of
+// an int16, like in the binary format.
+func NewKerningTable(kernings []Kerning) *KerningTable {
+	sorted := Normalize(&Font{Kernings: kernings}).Kernings
+
+	t := &KerningTable{
+		offsets: make([]int32, 0, 1),
+		seconds: make([]rune, len(sorted)),
+		amounts: make([]int16, len(sorted)),
+	}
+	for i, k := range sorted {
+		if len(t.firsts) == 0 || t.firsts[len(t.firsts)-1] != k.First {
+			t.firsts = append(t.firsts, k.First)
+			t.offsets = append(t.offsets, int32(i))
+		}
+		t.seconds[i] = k.Second
+		t.amounts[i] = clampInt16(k.Amount)
+	}
+	t.offsets = append(t.offsets, int32(len(sorted)))
+
+	return t
+}
+
+func clampInt16(v int) int16 {
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+// Len returns the number of kerning pairs
+func (t *KerningTable) Len() int {
+	return len(t.seconds)
+}
+
+// Lookup returns the kerning amount between first and second, or 0 if there is no such pair
+func (t *KerningTable) Lookup(first, second rune) int {
+	i := sort.Search(len(t.firsts), func(i int) bool { return t.firsts[i] >= first })
+	if i == len(t.firsts) || t.firsts[i] != first {
+		return 0
+	}
+
+	start, end := int(t.offsets[i]), int(t.offsets[i+1])
+	seconds := t.seconds[start:end]
+	j := sort.Search(len(seconds), func(j int) bool { return seconds[j] >= second })
+	if j == len(seconds) || seconds[j] != second {
+		return 0
+	}
+	return int(t.amounts[start+j])
+}
+
+// Kernings converts the table back to kerning pairs, sorted by first and second char
+func (t *KerningTable) Kernings() []Kerning {
+	kernings := make([]Kerning, 0, t.Len())
+	for i, first := range t.firsts {
+		for j := t.offsets[i]; j < t.offsets[i+1]; j++ {
+			kernings = append(kernings, Kerning{
+				First:  first,
+				Second: t.seconds[j],
+				Amount: int(t.amounts[j]),
+			})
+		}
+	}
+	return kernings
+}
+
+// KerningOptions controls how SynthesizeKerning measures glyphs
+type KerningOptions struct {
+	// Runes are the chars that are paired with each other.
+	// It defaults to the printable ASCII chars of the font.
+	Runes []rune
+	// Threshold is the smallest absolute amount for which a pair is created
+	Threshold int
+	// MaxPairs limits the number of created pairs, keeping the ones with the largest amounts.
+	// Zero means no limit.
+	MaxPairs int
+	// Coverage is the smallest channel value that counts as part of a glyph.
+	// Zero means 128.
+	Coverage uint8
+}
+
+// SynthesizeKerning adds optical kerning pairs to fonts that were exported without them, see KerningOptions.SynthesizeKerning
+func SynthesizeKerning(fnt *Font, pages []*image.NRGBA) (*Font, error) {
+	return KerningOptions{}.SynthesizeKerning(fnt, pages)
+}
+
+// SynthesizeKerning adds optical kerning pairs computed from the glyph bitmaps.
+// For every row of the line it measures how far the right edge of the first glyph is
+// from the left edge of the second glyph at its advance. The amount of a pair is the
+// difference between its smallest distance and the median smallest distance of all pairs,
+// so glyphs that leave an unusually large gap, like "AV" or "To", are moved closer.
+// Existing pairs of fnt are kept and the new pairs are appended to them.
+// The returned font is a copy with the chars and pages of fnt unchanged.
+func (opts KerningOptions) SynthesizeKerning(fnt *Font, pages []*image.NRGBA) (*Font, error) {
+	if err := checkGlyphs(&Font{Chars: fnt.Chars}, pages); err != nil {
+		return nil, err
+	}
+	if opts.Coverage == 0 {
+		opts.Coverage = 128
+	}
+	if opts.Threshold < 1 {
+		opts.Threshold = 1
+	}
+
+	chars := map[rune]Char{}
+	for _, c := range fnt.Chars {
+		chars[c.Id] = c
+	}
+	runes := opts.Runes
+	if runes == nil {
+		for r := rune(0x21); r <= 0x7e; r++ {
+			runes = append(runes, r)
+		}
+	}
+
+	profiles := map[rune]*glyphProfile{}
+	for _, r := range runes {
+		if c, ok := chars[r]; ok {
+			if p := newGlyphProfile(fnt, c, pages, opts.Coverage); p != nil {
+				profiles[r] = p
+			}
+		}
+	}
+
+	existing := map[[2]rune]bool{}
+	for _, k := range fnt.Kernings {
+		existing[[2]rune{k.First, k.Second}] = true
+	}
+
+	var measured []Kerning
+	var gaps []int
+	for _, first := range runes {
+		for _, second := range runes {
+			pl, pr := profiles[first], profiles[second]
+			if pl == nil || pr == nil {
+				continue
+			}
+			if gap, ok := pl.gap(pr, chars[first].XAdvance); ok {
+				measured = append(measured, Kerning{First: first, Second: second, Amount: gap})
+				gaps = append(gaps, gap)
+			}
+		}
+	}
+	if len(gaps) == 0 {
+		return withKernings(fnt, nil), nil
+	}
+	sort.Ints(gaps)
+	target := gaps[len(gaps)/2]
+
+	var created []Kerning
+	for _, k := range measured {
+		k.Amount = target - k.Amount
+		if k.Amount > -opts.Threshold && k.Amount < opts.Threshold {
+			continue
+		}
+		if existing[[2]rune{k.First, k.Second}] {
+			continue
+		}
+		created = append(created, k)
+	}
+
+	if opts.MaxPairs > 0 && len(created) > opts.MaxPairs {
+		sort.SliceStable(created, func(i, j int) bool {
+			return abs(created[i].Amount) > abs(created[j].Amount)
+		})
+		created = created[:opts.MaxPairs]
+	}
+
+	return withKernings(fnt, created), nil
+}
+
+// withKernings returns a copy of fnt with the kerning pairs added.
+// The chars and pages keep their order and ids, so that they still match the page images.
+func withKernings(fnt *Font, kernings []Kerning) *Font {
+	out := *fnt
+	out.Pages = append([]Page(nil), fnt.Pages...)
+	out.Chars = append([]Char(nil), fnt.Chars...)
+	out.Kernings = append(append([]Kerning(nil), fnt.Kernings...), kernings...)
+	return &out
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// glyphProfile holds the horizontal extent of a glyph for each row of the line,
+// relative to the pen position
+type glyphProfile struct {
+	top         int
+	left, right []int
+}
+
+// newGlyphProfile measures a glyph or returns nil if it has no covered pixels
+func newGlyphProfile(fnt *Font, c Char, pages []*image.NRGBA, coverage uint8) *glyphProfile {
+	if c.Width <= 0 || c.Height <= 0 {
+		return nil
+	}
+
+	channel := glyphChannel(fnt, c)
+	img := pages[c.Page]
+	origin := img.Bounds().Min.Add(image.Pt(c.X, c.Y))
+
+	p := &glyphProfile{
+		top:   c.YOffset,
+		left:  make([]int, c.Height),
+		right: make([]int, c.Height),
+	}
+	empty := true
+	for y := 0; y < c.Height; y++ {
+		p.left[y], p.right[y] = math.MaxInt32, math.MinInt32
+		for x := 0; x < c.Width; x++ {
+			if channelValue(img.NRGBAAt(origin.X+x, origin.Y+y), channel) < coverage {
+				continue
+			}
+			if p.left[y] == math.MaxInt32 {
+				p.left[y] = c.XOffset + x
+			}
+			p.right[y] = c.XOffset + x
+			empty = false
+		}
+	}
+	if empty {
+		return nil
+	}
+	return p
+}
+
+// gap returns the smallest horizontal distance between p and next when next is placed at advance.
+// It returns false if the glyphs share no covered rows.
+func (p *glyphProfile) gap(next *glyphProfile, advance int) (int, bool) {
+	min, found := math.MaxInt32, false
+	for y := range p.right {
+		ny := y + p.top - next.top
+		if ny < 0 || ny >= len(next.left) || p.right[y] == math.MinInt32 || next.left[ny] == math.MaxInt32 {
+			continue
+		}
+		if gap := advance + next.left[ny] - p.right[y] - 1; gap < min {
+			min = gap
+		}
+		found = true
+	}
+	return min, found
+}
+
+// glyphChannel returns the index of the color channel (0 to 3 for RGBA) that holds the glyph of c
+func glyphChannel(fnt *Font, c Char) int {
+	if fnt.Common.Packed {
+		switch {
+		case c.Channel&Alpha != 0:
+			return 3
+		case c.Channel&Red != 0:
+			return 0
+		case c.Channel&Green != 0:
+			return 1
+		case c.Channel&Blue != 0:
+			return 2
+		}
+	}
+
+	holdsGlyph := func(data ChannelData) bool {
+		return data == Glyph || data == GlyphAndOutline
+	}
+	switch {
+	case holdsGlyph(fnt.Common.AlphaChannel):
+		return 3
+	case holdsGlyph(fnt.Common.RedChannel):
+		return 0
+	case holdsGlyph(fnt.Common.GreenChannel):
+		return 1
+	case holdsGlyph(fnt.Common.BlueChannel):
+		return 2
+	}
+	return 3
+}
+
+func channelValue(c color.NRGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	case 2:
+		return c.B
+	}
+	return c.A
+}
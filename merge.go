@@ -0,0 +1,193 @@
+package bmf
+
+import "fmt"
+
+// MergeConflict decides which char is used when several fonts contain the same id
+type MergeConflict int
+
+// Conflict resolutions
+const (
+	// KeepFirst uses the char of the first font that contains it
+	KeepFirst MergeConflict = iota
+	// KeepLast uses the char of the last font that contains it
+	KeepLast
+	// FailOnConflict makes Merge return an error
+	FailOnConflict
+)
+
+// MergeOptions controls how Merge combines fonts.
+// The zero value keeps the first of conflicting chars.
+type MergeOptions struct {
+	// Conflict decides which char and kerning pair is used when several fonts contain the same one.
+	// The invalid char with id -1 is always taken from the first font that has it.
+	// Duplicates within one font are no conflict, the last one is kept like in Normalize.
+	Conflict MergeConflict
+}
+
+// IncompatibleError is returned by Merge when fonts cannot be combined
+type IncompatibleError struct {
+	// Font is the index of the font that differs from the first one
+	Font int
+	// Field is the name of the differing field
+	Field string
+	// Reason describes why the field is incompatible.
+	// It is empty when the field differs from the first font.
+	Reason string
+}
+
+func (e IncompatibleError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("font %v is incompatible: %v %v", e.Font, e.Field, e.Reason)
+	}
+	return fmt.Sprintf("font %v is incompatible: %v differs from the first font", e.Font, e.Field)
+}
+
+// Merge combines fonts into one, see MergeOptions.Merge
+func Merge(fonts ...*Font) (*Font, error) {
+	return MergeOptions{}.Merge(fonts...)
+}
+
+// Merge combines fonts into one.
+// Info is taken from the first font. The pages of all fonts are concatenated and
+// the chars renumbered to match. The baselines are aligned to the largest Common.Base
+// by adjusting Char.YOffset, and Common.LineHeight grows to fit all fonts.
+// Kerning pairs are dropped when one of their chars was taken from another font.
+// The fonts must agree on the page size, packing, channel layout and charset,
+// and their pages must have different file names.
+func (opts MergeOptions) Merge(fonts ...*Font) (*Font, error) {
+	if len(fonts) == 0 {
+		return &Font{}, nil
+	}
+
+	first := fonts[0]
+	for i, fnt := range fonts[1:] {
+		if field := incompatibleField(first, fnt); field != "" {
+			return nil, IncompatibleError{Font: i + 1, Field: field}
+		}
+	}
+
+	merged := &Font{
+		Info:          first.Info,
+		Common:        first.Common,
+		DistanceField: first.DistanceField,
+	}
+
+	for _, fnt := range fonts {
+		if fnt.Common.Base > merged.Common.Base {
+			merged.Common.Base = fnt.Common.Base
+		}
+	}
+
+	pageFont := map[string]int{}
+	charIdx := map[rune]int{}
+	kernIdx := map[[2]rune]int{}
+	charFont := map[rune]int{}
+	kernFont := map[[2]rune]int{}
+	merged.Common.LineHeight = 0
+	for i, fnt := range fonts {
+		shift := merged.Common.Base - fnt.Common.Base
+		if h := fnt.Common.LineHeight + shift; h > merged.Common.LineHeight {
+			merged.Common.LineHeight = h
+		}
+
+		pageOffset := len(merged.Pages)
+		for _, p := range fnt.Pages {
+			if j, exists := pageFont[p.File]; exists {
+				return nil, IncompatibleError{Font: i, Field: "Page.File", Reason: fmt.Sprintf("%q is also used by font %d", p.File, j)}
+			}
+			pageFont[p.File] = i
+			p.Id += pageOffset
+			merged.Pages = append(merged.Pages, p)
+		}
+
+		for _, c := range fnt.Chars {
+			c.Page += pageOffset
+			c.YOffset += shift
+
+			idx, exists := charIdx[c.Id]
+			if !exists {
+				charIdx[c.Id] = len(merged.Chars)
+				charFont[c.Id] = i
+				merged.Chars = append(merged.Chars, c)
+				continue
+			}
+			if charFont[c.Id] == i {
+				// a duplicate within the font, the last one is kept like in Normalize
+				merged.Chars[idx] = c
+				continue
+			}
+			if c.Id == -1 {
+				continue
+			}
+			switch opts.Conflict {
+			case KeepLast:
+				merged.Chars[idx] = c
+				charFont[c.Id] = i
+			case FailOnConflict:
+				return nil, fmt.Errorf("char %d is contained in font %d and %d", c.Id, charFont[c.Id], i)
+			}
+		}
+	}
+	merged.Common.Pages = len(merged.Pages)
+
+	// the kerning pairs are only taken from the fonts whose chars were kept
+	for i, fnt := range fonts {
+		for _, k := range fnt.Kernings {
+			if j, ok := charFont[k.First]; ok && j != i {
+				continue
+			}
+			if j, ok := charFont[k.Second]; ok && j != i {
+				continue
+			}
+
+			pair := [2]rune{k.First, k.Second}
+			idx, exists := kernIdx[pair]
+			if !exists {
+				kernIdx[pair] = len(merged.Kernings)
+				kernFont[pair] = i
+				merged.Kernings = append(merged.Kernings, k)
+				continue
+			}
+			if kernFont[pair] == i {
+				merged.Kernings[idx] = k
+				continue
+			}
+			switch opts.Conflict {
+			case KeepLast:
+				merged.Kernings[idx] = k
+				kernFont[pair] = i
+			case FailOnConflict:
+				return nil, fmt.Errorf("kerning pair %d,%d is contained in font %d and %d", k.First, k.Second, kernFont[pair], i)
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// incompatibleField returns the name of the first field that keeps a and b from being merged
+func incompatibleField(a, b *Font) string {
+	switch {
+	case a.Common.ScaleW != b.Common.ScaleW:
+		return "Common.ScaleW"
+	case a.Common.ScaleH != b.Common.ScaleH:
+		return "Common.ScaleH"
+	case a.Common.Packed != b.Common.Packed:
+		return "Common.Packed"
+	case a.Common.AlphaChannel != b.Common.AlphaChannel:
+		return "Common.AlphaChannel"
+	case a.Common.RedChannel != b.Common.RedChannel:
+		return "Common.RedChannel"
+	case a.Common.GreenChannel != b.Common.GreenChannel:
+		return "Common.GreenChannel"
+	case a.Common.BlueChannel != b.Common.BlueChannel:
+		return "Common.BlueChannel"
+	case a.Info.Unicode != b.Info.Unicode:
+		return "Info.Unicode"
+	case !bool(a.Info.Unicode) && !a.Info.Charset.equal(b.Info.Charset):
+		return "Info.Charset"
+	case distanceField(a) != distanceField(b):
+		return "DistanceField"
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package bmf
+
+import "sort"
+
+// Normalize returns a canonical copy of fnt, so that equal fonts serialize to equal files.
+// The chars are sorted by id, the kerning pairs by first and second char and the pages by id.
+// Of duplicate chars, kerning pairs and pages the last one is kept, like a parser that
+// overwrites earlier entries would. The pages are renumbered from 0 and Char.Page and
+// Common.Pages are updated to match.
+func Normalize(fnt *Font) *Font {
+	norm := &Font{
+		Info:          fnt.Info,
+		Common:        fnt.Common,
+		DistanceField: fnt.DistanceField,
+	}
+
+	pages := map[int]Page{}
+	for _, p := range fnt.Pages {
+		pages[p.Id] = p
+	}
+	pageIds := make([]int, 0, len(pages))
+	for id := range pages {
+		pageIds = append(pageIds, id)
+	}
+	sort.Ints(pageIds)
+
+	pageIdx := make(map[int]int, len(pageIds))
+	norm.Pages = make([]Page, len(pageIds))
+	for i, id := range pageIds {
+		pageIdx[id] = i
+		norm.Pages[i] = Page{Id: i, File: pages[id].File}
+	}
+	norm.Common.Pages = len(norm.Pages)
+
+	chars := map[rune]Char{}
+	for _, c := range fnt.Chars {
+		if idx, ok := pageIdx[c.Page]; ok {
+			c.Page = idx
+		}
+		chars[c.Id] = c
+	}
+	norm.Chars = make([]Char, 0, len(chars))
+	for _, c := range chars {
+		norm.Chars = append(norm.Chars, c)
+	}
+	sort.Slice(norm.Chars, func(i, j int) bool {
+		return norm.Chars[i].Id < norm.Chars[j].Id
+	})
+
+	kernings := map[[2]rune]Kerning{}
+	for _, k := range fnt.Kernings {
+		kernings[[2]rune{k.First, k.Second}] = k
+	}
+	norm.Kernings = make([]Kerning, 0, len(kernings))
+	for _, k := range kernings {
+		norm.Kernings = append(norm.Kernings, k)
+	}
+	sort.Slice(norm.Kernings, func(i, j int) bool {
+		a, b := norm.Kernings[i], norm.Kernings[j]
+		return a.First < b.First || a.First == b.First && a.Second < b.Second
+	})
+
+	return norm
+}
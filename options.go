@@ -0,0 +1,155 @@
+package bmf
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ParseOptions limits the resources used while parsing untrusted fonts.
+// A limit of zero or less disables that limit.
+// The zero value is used by the package level Parse functions.
+type ParseOptions struct {
+	// MaxChars limits the number of characters
+	MaxChars int
+	// MaxKernings limits the number of kerning pairs
+	MaxKernings int
+	// MaxPages limits the number of pages
+	MaxPages int
+	// MaxLineLength limits the length of a line in bytes in the text format, not counting the line ending.
+	// Without it, lines of any length are read.
+	MaxLineLength int
+	// MaxInputSize limits the size of the whole input in bytes
+	MaxInputSize int
+	// MaxStringLength limits the length of the face and page file names in bytes
+	MaxStringLength int
+}
+
+// LimitError is returned when the input exceeds one of the limits of ParseOptions.
+// It is usually wrapped in a TextParseError or BinaryParseError.
+type LimitError struct {
+	// Limit is the name of the exceeded ParseOptions field
+	Limit string
+	Max   int
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("limit %v of %v exceeded", e.Limit, e.Max)
+}
+
+func exceeds(value, max int) bool {
+	return max > 0 && value > max
+}
+
+func (opts ParseOptions) checkCounts(chars, kernings, pages int) error {
+	if exceeds(chars, opts.MaxChars) {
+		return LimitError{Limit: "MaxChars", Max: opts.MaxChars}
+	}
+	if exceeds(kernings, opts.MaxKernings) {
+		return LimitError{Limit: "MaxKernings", Max: opts.MaxKernings}
+	}
+	if exceeds(pages, opts.MaxPages) {
+		return LimitError{Limit: "MaxPages", Max: opts.MaxPages}
+	}
+	return nil
+}
+
+func (opts ParseOptions) checkStringLength(n int) error {
+	if exceeds(n, opts.MaxStringLength) {
+		return LimitError{Limit: "MaxStringLength", Max: opts.MaxStringLength}
+	}
+	return nil
+}
+
+func (opts ParseOptions) checkInputSize(n int) error {
+	if exceeds(n, opts.MaxInputSize) {
+		return LimitError{Limit: "MaxInputSize", Max: opts.MaxInputSize}
+	}
+	return nil
+}
+
+// checkFont checks all limits that apply to a fully parsed font
+func (opts ParseOptions) checkFont(fnt *Font) error {
+	if err := opts.checkCounts(len(fnt.Chars), len(fnt.Kernings), len(fnt.Pages)); err != nil {
+		return err
+	}
+	if err := opts.checkStringLength(len(fnt.Info.Face)); err != nil {
+		return err
+	}
+	if err := opts.checkStringLength(len(fnt.Info.Charset.Name)); err != nil {
+		return err
+	}
+	for _, p := range fnt.Pages {
+		if err := opts.checkStringLength(len(p.File)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitInput wraps src so that reading more than MaxInputSize bytes fails with a LimitError
+func (opts ParseOptions) limitInput(src io.Reader) io.Reader {
+	if opts.MaxInputSize <= 0 {
+		return src
+	}
+	return &limitedReader{src: src, max: opts.MaxInputSize}
+}
+
+type limitedReader struct {
+	src  io.Reader
+	read int
+	max  int
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.read > lr.max {
+		return 0, LimitError{Limit: "MaxInputSize", Max: lr.max}
+	}
+	// allow reading one byte past the limit to tell an input of exactly max bytes from a larger one
+	if left := lr.max - lr.read + 1; len(p) > left {
+		p = p[:left]
+	}
+	n, err := lr.src.Read(p)
+	lr.read += n
+	if lr.read > lr.max {
+		return n, LimitError{Limit: "MaxInputSize", Max: lr.max}
+	}
+	return n, err
+}
+
+// contextReader fails with the error of ctx once it is done, so that parsing stops
+// even while it waits for a line or block that never ends
+type contextReader struct {
+	ctx context.Context
+	src io.Reader
+}
+
+// withContext wraps src so that reading fails when ctx is done
+func withContext(ctx context.Context, src io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return src
+	}
+	return &contextReader{ctx: ctx, src: src}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.src.Read(p)
+}
+
+// SerializeOptions controls the output of the serializers.
+// The zero value writes the font as is and is used by the package level Serialize functions.
+type SerializeOptions struct {
+	// Canonical writes the normalized font, see Normalize,
+	// so that semantically equal fonts produce equal files
+	Canonical bool
+}
+
+func (opts SerializeOptions) prepare(fnt *Font) *Font {
+	if opts.Canonical {
+		return Normalize(fnt)
+	}
+	return fnt
+}
@@ -0,0 +1,119 @@
+package bmf
+
+import "math"
+
+// Rounding specifies how scaled metrics are rounded to whole pixels
+type Rounding int
+
+// Rounding modes
+const (
+	// RoundNearest rounds half away from zero
+	RoundNearest Rounding = iota
+	// RoundDown rounds towards negative infinity
+	RoundDown
+	// RoundUp rounds towards positive infinity
+	RoundUp
+)
+
+func (r Rounding) round(v float64) int {
+	switch r {
+	case RoundDown:
+		return int(math.Floor(v))
+	case RoundUp:
+		return int(math.Ceil(v))
+	default:
+		return int(math.Round(v))
+	}
+}
+
+// ScaleOptions controls how Scale rounds the scaled metrics.
+// The zero value rounds to the nearest pixel.
+type ScaleOptions struct {
+	Rounding Rounding
+}
+
+// Scale returns a copy of fnt with its metrics scaled by factor, see ScaleOptions.Scale
+func Scale(fnt *Font, factor float64) *Font {
+	return ScaleOptions{}.Scale(fnt, factor)
+}
+
+// Scale returns a copy of fnt with its metrics scaled by factor.
+// This scales Info.Size, Common.LineHeight, Common.Base, the offsets and advances of the chars
+// and the kerning amounts. The glyph rectangles still describe the unscaled glyphs on the pages,
+// so renderers have to draw them scaled by factor as well.
+func (opts ScaleOptions) Scale(fnt *Font, factor float64) *Font {
+	round := func(v int) int {
+		return opts.Rounding.round(float64(v) * factor)
+	}
+
+	scaled := &Font{
+		Info:          fnt.Info,
+		Common:        fnt.Common,
+		DistanceField: fnt.DistanceField,
+		Pages:         append([]Page(nil), fnt.Pages...),
+	}
+	scaled.Info.Size = round(fnt.Info.Size)
+	scaled.Common.LineHeight = round(fnt.Common.LineHeight)
+	scaled.Common.Base = round(fnt.Common.Base)
+
+	scaled.Chars = make([]Char, len(fnt.Chars))
+	for i, c := range fnt.Chars {
+		c.XOffset = round(c.XOffset)
+		c.YOffset = round(c.YOffset)
+		c.XAdvance = round(c.XAdvance)
+		scaled.Chars[i] = c
+	}
+
+	scaled.Kernings = make([]Kerning, len(fnt.Kernings))
+	for i, k := range fnt.Kernings {
+		k.Amount = round(k.Amount)
+		scaled.Kernings[i] = k
+	}
+
+	return scaled
+}
+
+// FracChar is a char with metrics in fractional pixels for subpixel layout
+type FracChar struct {
+	Id rune
+	// X, Y, Width and Height locate the unscaled glyph on its page
+	X      int
+	Y      int
+	Width  int
+	Height int
+	// DrawWidth and DrawHeight are the size the glyph is drawn with
+	DrawWidth  float64
+	DrawHeight float64
+	XOffset    float64
+	YOffset    float64
+	XAdvance   float64
+	Page       int
+	Channel    Channel
+}
+
+// Frac returns the metrics of the char scaled by factor without rounding
+func (c Char) Frac(factor float64) FracChar {
+	return FracChar{
+		Id:         c.Id,
+		X:          c.X,
+		Y:          c.Y,
+		Width:      c.Width,
+		Height:     c.Height,
+		DrawWidth:  float64(c.Width) * factor,
+		DrawHeight: float64(c.Height) * factor,
+		XOffset:    float64(c.XOffset) * factor,
+		YOffset:    float64(c.YOffset) * factor,
+		XAdvance:   float64(c.XAdvance) * factor,
+		Page:       c.Page,
+		Channel:    c.Channel,
+	}
+}
+
+// ScaleFrac returns the chars of fnt with their metrics scaled by factor without rounding
+func ScaleFrac(fnt *Font, factor float64) []FracChar {
+	chars := make([]FracChar, len(fnt.Chars))
+	for i, c := range fnt.Chars {
+		chars[i] = c.Frac(factor)
+	}
+	return chars
+}
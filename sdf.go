@@ -0,0 +1,115 @@
+package bmf
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// ConvertSDF converts the glyphs of fnt into signed distance fields, which can be rendered sharply at any scale.
+// The glyph rectangles grow by spread pixels on every side, which is added to Info.Padding, and the
+// distances from -spread to spread pixels are stored in the alpha channel, with 128 at the edge of the glyph.
+// Pixels with at least half coverage count as inside of the glyph.
+// The spread is recorded in Font.DistanceField as a distance range of twice the spread.
+// It returns an updated copy of fnt along with new pages of the same size.
+func ConvertSDF(fnt *Font, pages []*image.NRGBA, spread int) (*Font, []*image.NRGBA, error) {
+	if err := checkGlyphs(fnt, pages); err != nil {
+		return nil, nil, err
+	}
+	if spread < 1 {
+		return nil, nil, fmt.Errorf("invalid spread %d", spread)
+	}
+	if fnt.Common.ScaleW <= 0 || fnt.Common.ScaleH <= 0 {
+		return nil, nil, fmt.Errorf("invalid page size %dx%d", fnt.Common.ScaleW, fnt.Common.ScaleH)
+	}
+
+	out := *fnt
+	out.Info.Padding.Up += spread
+	out.Info.Padding.Right += spread
+	out.Info.Padding.Down += spread
+	out.Info.Padding.Left += spread
+	out.Info.Outline = 0
+	out.Common.AlphaChannel = Glyph
+	out.Common.RedChannel = One
+	out.Common.GreenChannel = One
+	out.Common.BlueChannel = One
+	out.DistanceField = &DistanceField{FieldType: "sdf", DistanceRange: 2 * spread}
+
+	out.Chars = append([]Char(nil), fnt.Chars...)
+	out.Kernings = append([]Kerning(nil), fnt.Kernings...)
+	for i := range out.Chars {
+		c := &out.Chars[i]
+		if c.Width <= 0 || c.Height <= 0 {
+			continue
+		}
+		c.Width += 2 * spread
+		c.Height += 2 * spread
+		c.XOffset -= spread
+		c.YOffset -= spread
+		c.Channel = All
+	}
+
+	images, err := newPages(&out, fnt.Common.ScaleW, fnt.Common.ScaleH, pageFilePattern(fnt))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, c := range out.Chars {
+		if c.Width <= 0 || c.Height <= 0 {
+			continue
+		}
+		src := fnt.Chars[i]
+		drawSDF(images[c.Page], c, pages[src.Page], src, glyphChannel(fnt, src), spread)
+	}
+
+	return &out, images, nil
+}
+
+// drawSDF computes the distance field of the glyph src on srcImg and draws it to the rectangle of c on dst
+func drawSDF(dst *image.NRGBA, c Char, srcImg *image.NRGBA, src Char, channel, spread int) {
+	srcOrigin := srcImg.Bounds().Min.Add(image.Pt(src.X, src.Y))
+	inside := make([]bool, c.Width*c.Height)
+	for y := 0; y < src.Height; y++ {
+		for x := 0; x < src.Width; x++ {
+			v := channelValue(srcImg.NRGBAAt(srcOrigin.X+x, srcOrigin.Y+y), channel)
+			inside[(y+spread)*c.Width+x+spread] = v >= 128
+		}
+	}
+
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			in := inside[y*c.Width+x]
+
+			// the distance to the nearest pixel on the other side of the edge, searched up to spread
+			nearest := (spread + 1) * (spread + 1)
+			for dy := -spread; dy <= spread; dy++ {
+				for dx := -spread; dx <= spread; dx++ {
+					d := dx*dx + dy*dy
+					if d >= nearest {
+						continue
+					}
+					nx, ny := x+dx, y+dy
+					other := false
+					if nx >= 0 && nx < c.Width && ny >= 0 && ny < c.Height {
+						other = inside[ny*c.Width+nx]
+					}
+					if other != in {
+						nearest = d
+					}
+				}
+			}
+
+			// the edge lies halfway between the pixel centers
+			dist := math.Min(math.Sqrt(float64(nearest))-0.5, float64(spread))
+			if !in {
+				dist = -dist
+			}
+			v := 0.5 + dist/float64(2*spread)
+
+			i := dst.PixOffset(c.X+x, c.Y+y)
+			pix := dst.Pix[i : i+4 : i+4]
+			pix[0], pix[1], pix[2] = 0xff, 0xff, 0xff
+			pix[3] = uint8(math.Round(v * 255))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package bmf
+
+import "image"
+
+// Subset returns a copy of fnt that only contains the chars for runes and the invalid char with id -1.
+// Kerning pairs that reference removed chars are dropped.
+// The pages are left unchanged, see SubsetPages to also shrink them.
+func Subset(fnt *Font, runes []rune) *Font {
+	keep := make(map[rune]bool, len(runes)+1)
+	keep[-1] = true
+	for _, r := range runes {
+		keep[r] = true
+	}
+
+	sub := &Font{
+		Info:          fnt.Info,
+		Common:        fnt.Common,
+		DistanceField: fnt.DistanceField,
+		Pages:         append([]Page(nil), fnt.Pages...),
+	}
+
+	kept := make(map[rune]bool, len(keep))
+	for _, c := range fnt.Chars {
+		if keep[c.Id] {
+			sub.Chars = append(sub.Chars, c)
+			kept[c.Id] = true
+		}
+	}
+	for _, k := range fnt.Kernings {
+		if kept[k.First] && kept[k.Second] {
+			sub.Kernings = append(sub.Kernings, k)
+		}
+	}
+
+	return sub
+}
+
+// SubsetPages is like Subset but also repacks the remaining glyphs from pages into new, smaller pages.
+// The page size is the smallest power of two that fits all glyphs onto a single page,
+// but never larger than the original Common.ScaleW and Common.ScaleH.
+// The chars, Common and Pages of the returned font are updated to match the new page images.
+func SubsetPages(fnt *Font, pages []*image.NRGBA, runes []rune) (*Font, []*image.NRGBA, error) {
+	sub := Subset(fnt, runes)
+	if err := checkGlyphs(sub, pages); err != nil {
+		return nil, nil, err
+	}
+
+	maxW, maxH := fnt.Common.ScaleW, fnt.Common.ScaleH
+	width, height := maxW, maxH
+	chars := make([]Char, len(sub.Chars))
+	for w, h := 1, 1; w <= maxW && h <= maxH; {
+		copy(chars, sub.Chars)
+		if n, err := pack(chars, w, h, sub.Info.Spacing); err == nil && n == 1 {
+			width, height = w, h
+			break
+		}
+
+		// grow alternately in width and height, as long as the limit allows
+		if (w <= h && w*2 <= maxW) || h*2 > maxH {
+			w *= 2
+		} else {
+			h *= 2
+		}
+	}
+
+	return Repack(sub, pages, RepackOptions{
+		Width:    width,
+		Height:   height,
+		PageFile: pageFilePattern(fnt),
+	})
+}
@@ -2,11 +2,13 @@ package bmf
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
-	"strings"
 )
 
 // TextParseError contains info about where and why a parsing error occurred
@@ -16,8 +18,32 @@ type TextParseError struct {
 	Err        error
 }
 
+// TruncatedError is returned when a text or XML font has fewer chars or kerning pairs than
+// the count of the chars or kernings block, which usually means that the file was cut off.
+// In the text format it is wrapped in a TextParseError for the line with the count.
+type TruncatedError struct {
+	// Block is "chars" or "kernings"
+	Block    string
+	Expected int
+	Actual   int
+}
+
+func (e TruncatedError) Error() string {
+	return fmt.Sprintf("truncated %v: expected %v but got %v", e.Block, e.Expected, e.Actual)
+}
+
+// maxErrorLineLength limits how much of the line is included in the message of a TextParseError
+const maxErrorLineLength = 80
+
 func (e TextParseError) Error() string {
-	return fmt.Sprintf("format error in line %v: '%v'", e.LineNumber, e.Line)
+	line := e.Line
+	if len(line) > maxErrorLineLength {
+		line = line[:maxErrorLineLength] + "..."
+	}
+	if e.Err == nil {
+		return fmt.Sprintf("format error in line %v: '%v'", e.LineNumber, line)
+	}
+	return fmt.Sprintf("format error in line %v: '%v': %v", e.LineNumber, line, e.Err)
 }
 
 func (e TextParseError) Unwrap() error {
@@ -26,253 +52,589 @@ func (e TextParseError) Unwrap() error {
 
 func parsePadding(s string) Padding {
 	pad := Padding{}
-
-	v := strings.SplitN(s, ",", 4)
-	v = append(v, "0", "0", "0", "0")
-
-	atoi(&pad.Up, v[0])
-	atoi(&pad.Right, v[1])
-	atoi(&pad.Down, v[2])
-	atoi(&pad.Left, v[3])
-
+	parseIntList([]byte(s), &pad.Up, &pad.Right, &pad.Down, &pad.Left)
 	return pad
 }
 
 func parseSpacing(s string) Spacing {
 	sp := Spacing{}
+	parseIntList([]byte(s), &sp.Horizontal, &sp.Vertical)
+	return sp
+}
 
-	v := strings.SplitN(s, ",", 4)
-	v = append(v, "0", "0")
+// parseIntList parses a comma separated list of integers into dst.
+// Missing or malformed values are set to 0.
+func parseIntList(b []byte, dst ...*int) {
+	for _, d := range dst {
+		end := bytes.IndexByte(b, ',')
+		if end < 0 {
+			end = len(b)
+		}
+		*d, _ = parseInt(b[:end])
+		if end < len(b) {
+			end++
+		}
+		b = b[end:]
+	}
+}
 
-	atoi(&sp.Horizontal, v[0])
-	atoi(&sp.Vertical, v[1])
+// parseInt parses a signed decimal integer without allocating
+func parseInt(b []byte) (int, bool) {
+	neg := false
+	if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+		neg = b[0] == '-'
+		b = b[1:]
+	}
+	if len(b) == 0 || len(b) > 18 {
+		return 0, false
+	}
 
-	return sp
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+
+	if neg {
+		return -n, true
+	}
+	return n, true
 }
 
-// ParseText parses a bmf font file in text format
-func ParseText(src io.Reader) (fnt *Font, err error) {
-	var lineNr int
-	var line string
-	defer func() {
-		if err != nil {
-			err = TextParseError{
-				Line:       line,
-				LineNumber: lineNr,
-				Err:        err,
+var (
+	errExpectedTag      = errors.New("expected non empty tag")
+	errExpectedKeyValue = errors.New("expected key-value pair")
+	errExpectedQuote    = errors.New("expected \"")
+)
+
+// textTokenizer splits a line of the text format into its tag and key-value pairs.
+// It works directly on the bytes of the line, so the key and value slices
+// are only valid until the next call to reset.
+type textTokenizer struct {
+	line  []byte
+	pos   int
+	key   []byte
+	value []byte
+	err   error
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\v' || c == '\f'
+}
+
+func (t *textTokenizer) skipSpace() {
+	for t.pos < len(t.line) && isSpace(t.line[t.pos]) {
+		t.pos++
+	}
+}
+
+// reset starts tokenizing line and returns its tag
+func (t *textTokenizer) reset(line []byte) (tag []byte, err error) {
+	*t = textTokenizer{line: line}
+
+	t.skipSpace()
+	start := t.pos
+	for t.pos < len(t.line) && !isSpace(t.line[t.pos]) {
+		t.pos++
+	}
+	if start == t.pos {
+		return nil, errExpectedTag
+	}
+	return t.line[start:t.pos], nil
+}
+
+// next advances to the next key-value pair.
+// It returns false at the end of the line or when an error occurred.
+func (t *textTokenizer) next() bool {
+	if t.err != nil {
+		return false
+	}
+
+	t.skipSpace()
+	if t.pos >= len(t.line) {
+		return false
+	}
+
+	start := t.pos
+	for t.pos < len(t.line) && t.line[t.pos] != '=' {
+		if c := t.line[t.pos]; isSpace(c) || c == '"' {
+			t.err = errExpectedKeyValue
+			return false
+		}
+		t.pos++
+	}
+	if t.pos >= len(t.line) {
+		t.err = errExpectedKeyValue
+		return false
+	}
+	t.key = t.line[start:t.pos]
+	t.pos++
+
+	if t.pos < len(t.line) && t.line[t.pos] == '"' {
+		t.pos++
+		end := bytes.IndexByte(t.line[t.pos:], '"')
+		if end < 0 {
+			t.err = errExpectedQuote
+			return false
+		}
+		t.value = t.line[t.pos : t.pos+end]
+		t.pos += end + 1
+		return true
+	}
+
+	start = t.pos
+	for t.pos < len(t.line) && !isSpace(t.line[t.pos]) {
+		if c := t.line[t.pos]; c == '=' || c == '"' {
+			t.err = errExpectedKeyValue
+			return false
+		}
+		t.pos++
+	}
+	t.value = t.line[start:t.pos]
+	return true
+}
+
+// int returns the current value as a number or 0 if it is not one
+func (t *textTokenizer) int() int {
+	v, _ := parseInt(t.value)
+	return v
+}
+
+// string returns a copy of the current value
+func (t *textTokenizer) string() string {
+	return string(t.value)
+}
+
+// textParser builds a Font from the lines of a text format file
+type textParser struct {
+	ctx      context.Context
+	opts     ParseOptions
+	fnt      *Font
+	tok      textTokenizer
+	lineNr   int
+	line     []byte
+	chars    textCount
+	kernings textCount
+	// split starts a new font at each info line after the first one, see ParseAll
+	split   bool
+	hasInfo bool
+	// fonts are the finished fonts before the current one
+	fonts []*Font
+}
+
+// textCount is the count of a chars or kernings line
+type textCount struct {
+	lineNr int
+	line   string
+	count  int
+}
+
+// count remembers the count of the current line.
+// A missing or invalid count is -1, so that it is not checked.
+func (p *textParser) count(tok *textTokenizer) textCount {
+	c := textCount{lineNr: p.lineNr, line: string(p.line), count: -1}
+	for tok.next() {
+		if string(tok.key) == "count" {
+			c.count = parseCount(tok.value)
+		}
+	}
+	return c
+}
+
+// parseCount parses the count of a chars or kernings block, or returns -1 if it is invalid
+func parseCount(b []byte) int {
+	if v, ok := parseInt(b); ok {
+		return v
+	}
+	return -1
+}
+
+// check makes sure that there are at least as many records as the count of the line
+func (c textCount) check(block string, actual int) error {
+	if c.lineNr == 0 || actual >= c.count {
+		return nil
+	}
+	return TextParseError{
+		Line:       c.line,
+		LineNumber: c.lineNr,
+		Err:        TruncatedError{Block: block, Expected: c.count, Actual: actual},
+	}
+}
+
+// finish checks the parsed font for missing records
+func (p *textParser) finish() (*Font, error) {
+	if err := p.chars.check("chars", len(p.fnt.Chars)); err != nil {
+		return nil, err
+	}
+	if err := p.kernings.check("kernings", len(p.fnt.Kernings)); err != nil {
+		return nil, err
+	}
+	return p.fnt, nil
+}
+
+// nextFont finishes the current font and starts a new one
+func (p *textParser) nextFont() error {
+	fnt, err := p.finish()
+	if err != nil {
+		return err
+	}
+	p.fonts = append(p.fonts, fnt)
+	p.fnt = &Font{}
+	p.chars, p.kernings = textCount{}, textCount{}
+	return nil
+}
+
+// fail wraps err with the position of the current line
+func (p *textParser) fail(err error) error {
+	return TextParseError{
+		Line:       string(p.line),
+		LineNumber: p.lineNr,
+		Err:        err,
+	}
+}
+
+func (p *textParser) parseLine(line []byte) error {
+	p.lineNr++
+	p.line = line
+
+	if err := p.ctx.Err(); err != nil {
+		return p.fail(err)
+	}
+
+	tag, err := p.tok.reset(line)
+	if err != nil {
+		return p.fail(err)
+	}
+
+	if string(tag) == "info" {
+		if p.split && p.hasInfo {
+			if err := p.nextFont(); err != nil {
+				return err
 			}
 		}
-	}()
+		p.hasInfo = true
+	}
+
+	fnt, tok := p.fnt, &p.tok
+	switch string(tag) {
+	case "info":
+		fnt.Info = parseInfoText(tok)
+		if err = p.opts.checkStringLength(len(fnt.Info.Face)); err == nil {
+			err = p.opts.checkStringLength(len(fnt.Info.Charset.Name))
+		}
+	case "char":
+		fnt.Chars = append(fnt.Chars, parseCharText(tok))
+		if err = p.opts.checkCounts(len(fnt.Chars), 0, 0); err == nil {
+			err = checkChar(fnt.Chars[len(fnt.Chars)-1])
+		}
+	case "common":
+		fnt.Common = parseCommonText(tok)
+		err = checkChannels(fnt.Common)
+	case "distanceField":
+		fnt.DistanceField = parseDistanceFieldText(tok)
+		err = p.opts.checkStringLength(len(fnt.DistanceField.FieldType))
+	case "page":
+		page := parsePageText(tok)
+		fnt.Pages = append(fnt.Pages, page)
+		if err = p.opts.checkCounts(0, 0, len(fnt.Pages)); err == nil {
+			err = p.opts.checkStringLength(len(page.File))
+		}
+	case "kerning":
+		fnt.Kernings = append(fnt.Kernings, parseKerningPairText(tok))
+		err = p.opts.checkCounts(0, len(fnt.Kernings), 0)
+	case "chars":
+		p.chars = p.count(tok)
+	case "kernings":
+		p.kernings = p.count(tok)
+	default:
+		for tok.next() {
+		}
+	}
+	if tok.err != nil {
+		return p.fail(tok.err)
+	}
+	if err != nil {
+		return p.fail(err)
+	}
+	return nil
+}
+
+// scanLines is a bufio.SplitFunc that splits lines at "\n", "\r\n" and a lone "\r",
+// which may be mixed in one file. The line endings are not part of the lines.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	end := bytes.IndexByte(data, '\n')
+	search := data
+	if end >= 0 {
+		search = data[:end]
+	}
+	if cr := bytes.IndexByte(search, '\r'); cr >= 0 {
+		switch {
+		case cr+1 < len(data) && data[cr+1] == '\n':
+			return cr + 2, data[:cr], nil
+		case cr+1 < len(data) || atEOF:
+			return cr + 1, data[:cr], nil
+		}
+		// a "\n" may follow in the next read
+		return 0, nil, nil
+	}
+	if end >= 0 {
+		return end + 1, data[:end], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// ParseText parses a bmf font file in text format
+func ParseText(src io.Reader) (*Font, error) {
+	return ParseOptions{}.ParseText(src)
+}
+
+// ParseText parses a bmf font file in text format
+func (opts ParseOptions) ParseText(src io.Reader) (*Font, error) {
+	return opts.ParseTextContext(context.Background(), src)
+}
+
+// ParseTextContext parses a bmf font file in text format and stops once ctx is done.
+// The error of ctx is returned wrapped in a TextParseError.
+func ParseTextContext(ctx context.Context, src io.Reader) (*Font, error) {
+	return ParseOptions{}.ParseTextContext(ctx, src)
+}
 
-	fnt = &Font{}
+// ParseTextContext parses a bmf font file in text format and stops once ctx is done.
+// The error of ctx is returned wrapped in a TextParseError.
+func (opts ParseOptions) ParseTextContext(ctx context.Context, src io.Reader) (*Font, error) {
+	p := &textParser{ctx: ctx, opts: opts, fnt: &Font{}}
+	if err := p.parse(src); err != nil {
+		return nil, err
+	}
+	return p.finish()
+}
 
-	sc := bufio.NewScanner(src)
+// parse parses all lines of src
+func (p *textParser) parse(src io.Reader) error {
+	opts := p.opts
+	sc := bufio.NewScanner(withContext(p.ctx, opts.limitInput(src)))
+	sc.Split(scanLines)
+	if opts.MaxLineLength > 0 {
+		// the buffer also has to hold the line ending
+		sc.Buffer(nil, opts.MaxLineLength+2)
+	} else {
+		// the buffer grows to hold lines of any length
+		sc.Buffer(nil, math.MaxInt)
+	}
 	for sc.Scan() {
-		lineNr++
-		line = sc.Text()
-		tag, attribs, strs, err := parseTagText(line)
-		if err != nil {
-			return nil, err
+		line := sc.Bytes()
+		if exceeds(len(line), opts.MaxLineLength) {
+			p.lineNr++
+			p.line = nil
+			return p.fail(LimitError{Limit: "MaxLineLength", Max: opts.MaxLineLength})
 		}
-		switch tag {
-		case "info":
-			fnt.Info = parseInfoText(attribs, strs)
-		case "char":
-			fnt.Chars = append(fnt.Chars, parseCharText(attribs))
-		case "common":
-			fnt.Common = parseCommonText(attribs)
-		case "page":
-			fnt.Pages = append(fnt.Pages, parsePageText(attribs, strs))
-		case "kerning":
-			fnt.Kernings = append(fnt.Kernings, parseKerningPairText(attribs))
+		if err := p.parseLine(line); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		// the failing line was never returned by the scanner
+		p.lineNr++
+		p.line = nil
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = LimitError{Limit: "MaxLineLength", Max: opts.MaxLineLength}
 		}
+		return p.fail(err)
 	}
-	return fnt, sc.Err()
+	return nil
+}
+
+// ParseTextBytes parses a bmf font file in text format.
+// The lines are tokenized in place without copying data.
+func ParseTextBytes(data []byte) (*Font, error) {
+	return ParseOptions{}.ParseTextBytes(data)
 }
 
-func parsePageText(attribs map[string]int, strs []string) Page {
+// ParseTextBytes parses a bmf font file in text format.
+// The lines are tokenized in place without copying data.
+func (opts ParseOptions) ParseTextBytes(data []byte) (*Font, error) {
+	if err := opts.checkInputSize(len(data)); err != nil {
+		return nil, err
+	}
+
+	p := &textParser{ctx: context.Background(), opts: opts, fnt: &Font{}}
+
+	for len(data) > 0 {
+		advance, line, _ := scanLines(data, true)
+		data = data[advance:]
+		if exceeds(len(line), opts.MaxLineLength) {
+			p.lineNr++
+			p.line = nil
+			return nil, p.fail(LimitError{Limit: "MaxLineLength", Max: opts.MaxLineLength})
+		}
+		if err := p.parseLine(line); err != nil {
+			return nil, err
+		}
+	}
+	return p.finish()
+}
+
+func parsePageText(tok *textTokenizer) Page {
 	page := Page{}
-	for k, v := range attribs {
-		switch k {
+	for tok.next() {
+		switch string(tok.key) {
 		case "id":
-			page.Id = v
+			page.Id = tok.int()
 		case "file":
-			page.File = strs[v]
+			page.File = tok.string()
 		}
 	}
 	return page
 }
 
-func parseInfoText(attribs map[string]int, strs []string) Info {
+func parseDistanceFieldText(tok *textTokenizer) *DistanceField {
+	df := &DistanceField{}
+	for tok.next() {
+		switch string(tok.key) {
+		case "fieldType":
+			df.FieldType = tok.string()
+		case "distanceRange":
+			df.DistanceRange = tok.int()
+		}
+	}
+	return df
+}
+
+func parseInfoText(tok *textTokenizer) Info {
 	info := Info{}
-	for k, v := range attribs {
-		switch k {
+	for tok.next() {
+		switch string(tok.key) {
 		case "size":
-			info.Size = v
+			info.Size = tok.int()
 		case "face":
-			info.Face = strs[v]
+			info.Face = tok.string()
 		case "bold":
-			info.Bold = Bool(v)
+			info.Bold = Bool(tok.int())
 		case "italic":
-			info.Italic = Bool(v)
+			info.Italic = Bool(tok.int())
 		case "charset":
-			info.Charset = strs[v]
+			info.Charset = ParseCharset(tok.string())
 		case "unicode":
-			info.Unicode = Bool(v)
+			info.Unicode = Bool(tok.int())
 		case "stretchH":
-			info.StretchH = v
+			info.StretchH = tok.int()
 		case "smooth":
-			info.Smooth = Bool(v)
+			info.Smooth = Bool(tok.int())
 		case "aa":
-			info.AA = v
+			info.AA = tok.int()
 		case "padding":
-			info.Padding = parsePadding(strs[v])
+			parseIntList(tok.value, &info.Padding.Up, &info.Padding.Right, &info.Padding.Down, &info.Padding.Left)
 		case "spacing":
-			info.Spacing = parseSpacing(strs[v])
+			parseIntList(tok.value, &info.Spacing.Horizontal, &info.Spacing.Vertical)
 		case "outline":
-			info.Outline = v
+			info.Outline = tok.int()
 		}
 	}
 
 	return info
 }
 
-func parseCharText(attribs map[string]int) Char {
+func parseCharText(tok *textTokenizer) Char {
 	char := Char{}
-	for k, v := range attribs {
-		switch k {
+	for tok.next() {
+		switch string(tok.key) {
 		case "id":
-			char.Id = rune(v)
+			char.Id = rune(tok.int())
 		case "x":
-			char.X = v
+			char.X = tok.int()
 		case "y":
-			char.Y = v
+			char.Y = tok.int()
 		case "width":
-			char.Width = v
+			char.Width = tok.int()
 		case "height":
-			char.Height = v
+			char.Height = tok.int()
 		case "xoffset":
-			char.XOffset = v
+			char.XOffset = tok.int()
 		case "yoffset":
-			char.YOffset = v
+			char.YOffset = tok.int()
 		case "xadvance":
-			char.XAdvance = v
+			char.XAdvance = tok.int()
 		case "page":
-			char.Page = v
+			char.Page = tok.int()
 		case "chnl":
-			char.Channel = Channel(v)
+			char.Channel = Channel(tok.int())
 		}
 	}
 
 	return char
 }
 
-func parseCommonText(attribs map[string]int) Common {
+func parseCommonText(tok *textTokenizer) Common {
 	common := Common{}
-
-	for k, v := range attribs {
-		switch k {
+	for tok.next() {
+		switch string(tok.key) {
 		case "lineHeight":
-			common.LineHeight = v
+			common.LineHeight = tok.int()
 		case "base":
-			common.Base = v
+			common.Base = tok.int()
 		case "scaleW":
-			common.ScaleW = v
+			common.ScaleW = tok.int()
 		case "scaleH":
-			common.ScaleH = v
+			common.ScaleH = tok.int()
 		case "pages":
-			common.Pages = v
+			common.Pages = tok.int()
 		case "packed":
-			common.Packed = Bool(v)
+			common.Packed = Bool(tok.int())
 		case "alphaChnl":
-			common.AlphaChannel = ChannelData(v)
+			common.AlphaChannel = ChannelData(tok.int())
 		case "redChnl":
-			common.RedChannel = ChannelData(v)
+			common.RedChannel = ChannelData(tok.int())
 		case "greenChnl":
-			common.GreenChannel = ChannelData(v)
+			common.GreenChannel = ChannelData(tok.int())
 		case "blueChnl":
-			common.BlueChannel = ChannelData(v)
+			common.BlueChannel = ChannelData(tok.int())
 		}
 	}
 
 	return common
 }
 
-func parseKerningPairText(attribs map[string]int) Kerning {
+func parseKerningPairText(tok *textTokenizer) Kerning {
 	kern := Kerning{}
-	for k, v := range attribs {
-		switch k {
+	for tok.next() {
+		switch string(tok.key) {
 		case "first":
-			kern.First = rune(v)
+			kern.First = rune(tok.int())
 		case "second":
-			kern.Second = rune(v)
+			kern.Second = rune(tok.int())
 		case "amount":
-			kern.Amount = v
+			kern.Amount = tok.int()
 		}
 	}
 	return kern
 }
 
-func parseTagText(line string) (name string, values map[string]int, strs []string, err error) {
-	values = map[string]int{}
-	strs = []string{}
-
-	var stripped string
-	rd := bufio.NewReader(strings.NewReader(line))
-	for {
-		start, err := rd.ReadString('"')
-		stripped += start
-		if errors.Is(err, io.EOF) {
-			break
-		} else if err != nil {
-			return "", nil, nil, err
-		}
-		str, err := rd.ReadString('"')
-		if errors.Is(err, io.EOF) {
-			return "", nil, nil, fmt.Errorf("expected \"")
-		}
-		strs = append(strs, str[:len(str)-1])
-	}
-
-	fields := strings.Fields(stripped)
-	if len(fields) == 0 {
-		return "", nil, nil, fmt.Errorf("expected non empty tag")
-	}
-
-	strIdx := 0
-	for i, f := range fields {
-		if i == 0 {
-			name = f
-			continue
-		}
-
-		kv := strings.Split(f, "=")
-		if len(kv) != 2 {
-			return "", nil, nil, fmt.Errorf("expected key-value pair")
-		}
-		key, value := kv[0], kv[1]
-		if value == "\"" {
-			values[key] = strIdx
-			strIdx++
-		} else if num, err := strconv.Atoi(value); err == nil {
-			values[key] = num
-		} else {
-			strs = append(strs, value)
-			values[key] = len(strs) - 1
-		}
-	}
-
-	return
+// SerializeText serializes a bmf font file in text format
+func SerializeText(fnt *Font, dst io.Writer) error {
+	return SerializeOptions{}.SerializeText(fnt, dst)
 }
 
 // SerializeText serializes a bmf font file in text format
-func SerializeText(fnt *Font, dst io.Writer) error {
+func (opts SerializeOptions) SerializeText(fnt *Font, dst io.Writer) error {
+	fnt = opts.prepare(fnt)
 	if err := serializeInfoBlockText(fnt, dst); err != nil {
 		return err
 	}
 	if err := serializeCommonBlockText(fnt, dst); err != nil {
 		return err
 	}
+	if err := serializeDistanceFieldText(fnt, dst); err != nil {
+		return err
+	}
 	if err := serializePagesBlockText(fnt, dst); err != nil {
 		return err
 	}
@@ -289,7 +651,7 @@ func serializeInfoBlockText(fnt *Font, dst io.Writer) error {
 	i := fnt.Info
 
 	_, err := fmt.Fprintf(dst, "info face=%q size=%d bold=%d italic=%d charset=%q unicode=%d stretchH=%d smooth=%d aa=%d ",
-		i.Face, i.Size, i.Bold.Byte(), i.Italic.Byte(), i.Charset, i.Unicode.Byte(), i.StretchH, i.Smooth.Byte(), i.AA)
+		i.Face, i.Size, i.Bold.Byte(), i.Italic.Byte(), i.Charset.String(), i.Unicode.Byte(), i.StretchH, i.Smooth.Byte(), i.AA)
 	if err != nil {
 		return err
 	}
@@ -315,6 +677,20 @@ func serializeCommonBlockText(fnt *Font, dst io.Writer) error {
 	return err
 }
 
+func serializeDistanceFieldText(fnt *Font, dst io.Writer) error {
+	if fnt.DistanceField == nil {
+		return nil
+	}
+	// the tokenizer does not unescape values, so they must not need escaping
+	fieldType := fnt.DistanceField.FieldType
+	if strconv.Quote(fieldType) != `"`+fieldType+`"` {
+		return fmt.Errorf("distance field type %q cannot be written in text format", fieldType)
+	}
+	_, err := fmt.Fprintf(dst, "distanceField fieldType=%q distanceRange=%d\n",
+		fieldType, fnt.DistanceField.DistanceRange)
+	return err
+}
+
 func serializePagesBlockText(fnt *Font, dst io.Writer) error {
 	for _, p := range fnt.Pages {
 		_, err := fmt.Fprintf(dst, "page id=%d file=%q\n", p.Id, p.File)
@@ -362,3 +738,33 @@ func serializeKerningsBlockText(fnt *Font, dst io.Writer) error {
 	}
 	return nil
 }
+
+// MarshalText implements encoding.TextMarshaler using the text format
+func (font Font) MarshalText() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := SerializeText(&font, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using the text format
+func (font *Font) UnmarshalText(text []byte) error {
+	fnt, err := ParseTextBytes(text)
+	if err != nil {
+		return err
+	}
+	*font = *fnt
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the text format,
+// which unlike the binary format keeps Font.DistanceField
+func (font Font) GobEncode() ([]byte, error) {
+	return font.MarshalText()
+}
+
+// GobDecode implements gob.GobDecoder using the text format
+func (font *Font) GobDecode(data []byte) error {
+	return font.UnmarshalText(data)
+}
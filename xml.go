@@ -1,9 +1,14 @@
 package bmf
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 )
 
 // UnmarshalXMLAttr converts from format <up>,<right>,<down>,<left>
@@ -36,37 +41,323 @@ func (nb BinBool) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
 	return xml.Attr{Name: name, Value: "0"}, nil
 }
 
+// MarshalXMLAttr writes the channel data type as number, like the AngelCode generator
+func (d ChannelData) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strconv.Itoa(int(d))}, nil
+}
+
+// UnmarshalXMLAttr reads the channel data type as number or name
+func (d *ChannelData) UnmarshalXMLAttr(attr xml.Attr) error {
+	return d.UnmarshalText([]byte(attr.Value))
+}
+
+// MarshalXMLAttr writes the channel bits as number, like the AngelCode generator
+func (c Channel) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strconv.Itoa(int(c))}, nil
+}
+
+// UnmarshalXMLAttr reads the channel bits as number or names
+func (c *Channel) UnmarshalXMLAttr(attr xml.Attr) error {
+	return c.UnmarshalText([]byte(attr.Value))
+}
+
 type xmlFont struct {
-	XMLName  xml.Name  `xml:"font"`
-	Info     Info      `xml:"info"`
-	Common   Common    `xml:"common"`
-	Pages    []Page    `xml:"pages>page"`
-	Chars    []Char    `xml:"chars>char"`
-	Kernings []Kerning `xml:"kernings>kerning"`
+	XMLName       xml.Name       `xml:"font"`
+	Info          Info           `xml:"info"`
+	Common        Common         `xml:"common"`
+	DistanceField *DistanceField `xml:"distanceField,omitempty"`
+	Pages         []Page         `xml:"pages>page"`
+	Chars         xmlChars       `xml:"chars"`
+	Kernings      *xmlKernings   `xml:"kernings,omitempty"`
+}
+
+// xmlChars is the chars element with its count attribute
+type xmlChars struct {
+	Count int    `xml:"count,attr"`
+	Chars []Char `xml:"char"`
+}
+
+// xmlKernings is the kernings element with its count attribute
+type xmlKernings struct {
+	Count    int       `xml:"count,attr"`
+	Kernings []Kerning `xml:"kerning"`
 }
 
 // MarshalXML converts a Font struct to XML
 func (font Font) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	start.Name.Local = "font"
-	return e.EncodeElement(xmlFont{
-		Info:     font.Info,
-		Common:   font.Common,
-		Pages:    font.Pages,
-		Chars:    font.Chars,
-		Kernings: font.Kernings,
-	}, start)
+	x := xmlFont{
+		Info:          font.Info,
+		Common:        font.Common,
+		DistanceField: font.DistanceField,
+		Pages:         font.Pages,
+		Chars:         xmlChars{Count: len(font.Chars), Chars: font.Chars},
+	}
+	if len(font.Kernings) > 0 {
+		x.Kernings = &xmlKernings{Count: len(font.Kernings), Kernings: font.Kernings}
+	}
+	return e.EncodeElement(x, start)
+}
+
+// UnmarshalXML converts XML to a Font struct.
+// It returns a TruncatedError if there are fewer chars or kerning pairs than their count attribute.
+func (font *Font) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	fnt, err := ParseOptions{}.decodeFontXML(d, start)
+	if err != nil {
+		return err
+	}
+	*font = *fnt
+	return nil
+}
+
+// decodeFontXML decodes the font element start element by element,
+// so that the limits are checked before the next char, kerning pair or page is read
+func (opts ParseOptions) decodeFontXML(d *xml.Decoder, start xml.StartElement) (*Font, error) {
+	if start.Name.Local != "font" {
+		return nil, fmt.Errorf("expected element type <font> but have <%s>", start.Name.Local)
+	}
+
+	fnt := &Font{}
+	err := decodeChildrenXML(d, func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "info":
+			return d.DecodeElement(&fnt.Info, &start)
+		case "common":
+			return d.DecodeElement(&fnt.Common, &start)
+		case "distanceField":
+			fnt.DistanceField = &DistanceField{}
+			return d.DecodeElement(fnt.DistanceField, &start)
+		case "pages":
+			return decodeChildrenXML(d, func(start xml.StartElement) error {
+				if start.Name.Local != "page" {
+					return d.Skip()
+				}
+				var page Page
+				if err := d.DecodeElement(&page, &start); err != nil {
+					return err
+				}
+				fnt.Pages = append(fnt.Pages, page)
+				return opts.checkCounts(0, 0, len(fnt.Pages))
+			})
+		case "chars":
+			count := countXML(start)
+			err := decodeChildrenXML(d, func(start xml.StartElement) error {
+				if start.Name.Local != "char" {
+					return d.Skip()
+				}
+				var c Char
+				if err := d.DecodeElement(&c, &start); err != nil {
+					return err
+				}
+				fnt.Chars = append(fnt.Chars, c)
+				return opts.checkCounts(len(fnt.Chars), 0, 0)
+			})
+			if err != nil {
+				return err
+			}
+			if len(fnt.Chars) < count {
+				return TruncatedError{Block: "chars", Expected: count, Actual: len(fnt.Chars)}
+			}
+			return nil
+		case "kernings":
+			count := countXML(start)
+			err := decodeChildrenXML(d, func(start xml.StartElement) error {
+				if start.Name.Local != "kerning" {
+					return d.Skip()
+				}
+				var k Kerning
+				if err := d.DecodeElement(&k, &start); err != nil {
+					return err
+				}
+				fnt.Kernings = append(fnt.Kernings, k)
+				return opts.checkCounts(0, len(fnt.Kernings), 0)
+			})
+			if err != nil {
+				return err
+			}
+			if len(fnt.Kernings) < count {
+				return TruncatedError{Block: "kernings", Expected: count, Actual: len(fnt.Kernings)}
+			}
+			return nil
+		}
+		return d.Skip()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.checkFont(fnt); err != nil {
+		return nil, err
+	}
+	return fnt, nil
+}
+
+// decodeChildrenXML calls decode for each child element until the end of the current element.
+// decode has to consume the whole child element.
+func decodeChildrenXML(d *xml.Decoder, decode func(start xml.StartElement) error) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err := decode(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// countXML returns the count attribute of start, or -1 if it is missing or invalid like in the text format
+func countXML(start xml.StartElement) int {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "count" {
+			return parseCount([]byte(strings.TrimSpace(attr.Value)))
+		}
+	}
+	return -1
+}
+
+// SerializeXML serializes a bmf font file in XML format, including the XML header
+func SerializeXML(fnt *Font, dst io.Writer) error {
+	return SerializeOptions{}.SerializeXML(fnt, dst)
+}
+
+// SerializeXML serializes a bmf font file in XML format, including the XML header
+func (opts SerializeOptions) SerializeXML(fnt *Font, dst io.Writer) error {
+	fnt = opts.prepare(fnt)
+
+	if _, err := io.WriteString(dst, "<?xml version=\"1.0\"?>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(dst)
+	enc.Indent("", "  ")
+	if err := enc.Encode(fnt); err != nil {
+		return err
+	}
+	_, err := io.WriteString(dst, "\n")
+	return err
 }
 
 // ParseXML parses a bmf font file in XML format
 func ParseXML(src io.Reader) (*Font, error) {
-	data, err := io.ReadAll(src)
+	return ParseOptions{}.ParseXML(src)
+}
+
+// ParseXML parses a bmf font file in XML format
+func (opts ParseOptions) ParseXML(src io.Reader) (*Font, error) {
+	return opts.ParseXMLContext(context.Background(), src)
+}
+
+// ParseXMLContext parses a bmf font file in XML format and stops once ctx is done.
+// The error of ctx is returned wrapped in a TextParseError.
+func ParseXMLContext(ctx context.Context, src io.Reader) (*Font, error) {
+	return ParseOptions{}.ParseXMLContext(ctx, src)
+}
+
+// ParseXMLContext parses a bmf font file in XML format and stops once ctx is done.
+// The error of ctx is returned wrapped in a TextParseError.
+func (opts ParseOptions) ParseXMLContext(ctx context.Context, src io.Reader) (*Font, error) {
+	data, err := io.ReadAll(withContext(ctx, opts.limitInput(src)))
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return nil, failXML(data, len(data), err)
+		}
 		return nil, err
 	}
+	return opts.parseXMLBytes(ctx, data)
+}
 
-	fnt := &Font{}
-	if err := xml.Unmarshal(data, fnt); err != nil {
+// ParseXMLBytes parses a bmf font file in XML format
+func ParseXMLBytes(data []byte) (*Font, error) {
+	return ParseOptions{}.ParseXMLBytes(data)
+}
+
+// ParseXMLBytes parses a bmf font file in XML format
+func (opts ParseOptions) ParseXMLBytes(data []byte) (*Font, error) {
+	return opts.parseXMLBytes(context.Background(), data)
+}
+
+func (opts ParseOptions) parseXMLBytes(ctx context.Context, data []byte) (*Font, error) {
+	if err := opts.checkInputSize(len(data)); err != nil {
+		return nil, err
+	}
+
+	rd := bytes.NewReader(data)
+	d := xml.NewDecoder(withContext(ctx, rd))
+	fnt, err := opts.decodeXML(d)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return nil, failXML(data, len(data)-rd.Len(), err)
+		}
 		return nil, err
 	}
 	return fnt, nil
 }
+
+// decodeXML decodes the first element of d as font, like xml.Decoder.Decode
+func (opts ParseOptions) decodeXML(d *xml.Decoder) (*Font, error) {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return opts.decodeFontXML(d, start)
+		}
+	}
+}
+
+// parseAllXML decodes every font element of src, see ParseAll
+func (opts ParseOptions) parseAllXML(src io.Reader) ([]*Font, error) {
+	data, err := io.ReadAll(opts.limitInput(src))
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.checkInputSize(len(data)); err != nil {
+		return nil, err
+	}
+
+	var fonts []*Font
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "font" {
+			continue
+		}
+
+		fnt, err := opts.decodeFontXML(d, start)
+		if err != nil {
+			return nil, err
+		}
+		fonts = append(fonts, fnt)
+	}
+	if len(fonts) == 0 {
+		return nil, errors.New("no font element")
+	}
+	return fonts, nil
+}
+
+// failXML wraps err with the line of data that contains offset.
+// The decoder reads ahead, so it is the line where reading stopped rather than the element being decoded.
+func failXML(data []byte, offset int, err error) error {
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	line := data[start:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	return TextParseError{
+		LineNumber: bytes.Count(data[:offset], []byte("\n")) + 1,
+		Line:       string(bytes.TrimSuffix(line, []byte("\r"))),
+		Err:        err,
+	}
+}